@@ -0,0 +1,80 @@
+package ioc
+
+import "fmt"
+
+// Binding is an entry of ProvideOptions.Providers that the compiler has
+// type-checked. Create one with Override or Value.
+type Binding interface {
+	register(ctx *Context) error
+}
+
+// overrideBinding replaces target with provider
+type overrideBinding[T any] struct {
+	target   *Ref[T]
+	provider *Ref[T]
+}
+
+// register implements Binding
+func (b overrideBinding[T]) register(ctx *Context) error {
+	if b.target == nil || b.provider == nil {
+		return &ProviderError{Reason: "Override called with a nil ref"}
+	}
+	ctx.localProviders[b.target] = b.provider
+	return nil
+}
+
+// Override binds target to provider, which must provide the same type. Unlike
+// ProvideOptions.Overrides, the provider does not need to name its target and
+// can be reused for several targets.
+func Override[T any](target *Ref[T], provider *Ref[T]) Binding {
+	return overrideBinding[T]{target: target, provider: provider}
+}
+
+// Value binds target to a fixed instance. Values are never constructed, so
+// they take no part in cycle detection.
+func Value[T any](target *Ref[T], value T) Binding {
+	var opts []ProvideOptions[T]
+	if target != nil {
+		opts = append(opts, ProvideOptions[T]{Name: target.name})
+	}
+	provider := newRef(nil, opts)
+	provider.value, provider.isValue = value, true
+	provider.location = callerLocation(1)
+	return overrideBinding[T]{target: target, provider: provider}
+}
+
+// ProvideValue creates a ref whose instance is value. The value is not
+// constructed, cached, disposed or started, so of the options only Name and
+// Overrides apply.
+func ProvideValue[T any](value T, opts ...ProvideOptions[T]) *Ref[T] {
+	ref := newRef(nil, opts)
+	ref.value, ref.isValue = value, true
+	return ref
+}
+
+// registerProvider registers an entry of ProvideOptions.Providers in ctx. A
+// ref overrides the ref named by its Overrides option, or itself if it has
+// none.
+func registerProvider(ctx *Context, provider any) error {
+	switch p := provider.(type) {
+	case Binding:
+		return p.register(ctx)
+	case refMarker:
+		override := p.getOverride()
+		if override == nil {
+			ctx.localProviders[p] = p
+			return nil
+		}
+		target, ok := override.(refMarker)
+		if !ok {
+			return &ProviderError{Provider: p, Reason: fmt.Sprintf("Overrides is a %T, not a ref", override)}
+		}
+		if !target.accepts(p) {
+			return &ProviderError{Provider: p, Reason: fmt.Sprintf("cannot override %s, which provides a different type", target.describe())}
+		}
+		ctx.localProviders[target] = p
+		return nil
+	default:
+		return &ProviderError{Provider: provider, Reason: "not a ref or a Binding"}
+	}
+}
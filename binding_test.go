@@ -0,0 +1,194 @@
+package ioc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOverrideBinding(t *testing.T) {
+	t.Parallel()
+
+	type Config struct{ Env string }
+
+	configRef := Provide(func(ctx *Context) *Config { return &Config{Env: "production"} })
+	testConfigRef := Provide(func(ctx *Context) *Config { return &Config{Env: "test"} })
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, configRef).Env
+	}, ProvideOptions[string]{Providers: []any{Override(configRef, testConfigRef)}})
+
+	env := RunInContainer(NewContainer(), func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	if env != "test" {
+		t.Errorf("expected 'test', got '%s'", env)
+	}
+}
+
+func TestOverrideBindingReusesProviderForSeveralTargets(t *testing.T) {
+	t.Parallel()
+
+	primaryRef := Provide(func(ctx *Context) string { return "primary" })
+	replicaRef := Provide(func(ctx *Context) string { return "replica" })
+	memoryRef := Provide(func(ctx *Context) string { return "memory" })
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, primaryRef) + "," + Inject(ctx, replicaRef)
+	}, ProvideOptions[string]{Providers: []any{
+		Override(primaryRef, memoryRef),
+		Override(replicaRef, memoryRef),
+	}})
+
+	result := RunInContainer(NewContainer(), func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	if result != "memory,memory" {
+		t.Errorf("expected 'memory,memory', got '%s'", result)
+	}
+}
+
+func TestOverridesOfAnotherTypeIsRejected(t *testing.T) {
+	t.Parallel()
+
+	configRef := Provide(func(ctx *Context) string { return "production" })
+	// Held in an any, which iocvet does not check, as the mistake is deliberate
+	var target any = configRef
+	wrongRef := Provide(func(ctx *Context) int {
+		return 42
+	}, ProvideOptions[int]{Name: "Wrong", Overrides: target})
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, configRef)
+	}, ProvideOptions[string]{Providers: []any{wrongRef}})
+
+	err := RunInContainer(NewContainer(), func(ctx *Context) error {
+		_, err := InjectE(ctx, serviceRef)
+		return err
+	})
+
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("expected *ProviderError, got %v", err)
+	}
+	if providerErr.Provider != wrongRef {
+		t.Errorf("expected the offending ref, got %v", providerErr.Provider)
+	}
+	if !strings.Contains(err.Error(), `Invalid provider Ref[int] "Wrong"`) {
+		t.Errorf("expected the provider to be named, got '%v'", err)
+	}
+	var resErr *ResolutionError
+	if !errors.As(err, &resErr) || len(resErr.Chain) != 1 || resErr.Chain[0].Ref != serviceRef {
+		t.Errorf("expected the chain to end at the ref declaring the provider, got %v", err)
+	}
+}
+
+func TestInvalidProvidersAreRejected(t *testing.T) {
+	t.Parallel()
+
+	configRef := Provide(func(ctx *Context) string { return "production" })
+	var notARef any = "ConfigRef"
+	notARefRef := Provide(func(ctx *Context) string {
+		return "test"
+	}, ProvideOptions[string]{Overrides: notARef})
+
+	for name, provider := range map[string]any{
+		"overrides a non-ref": notARefRef,
+		"not a ref":           "ConfigRef",
+		"nil override":        Override(configRef, nil),
+	} {
+		serviceRef := Provide(func(ctx *Context) string {
+			return Inject(ctx, configRef)
+		}, ProvideOptions[string]{Providers: []any{provider}})
+
+		err := RunInContainer(NewContainer(), func(ctx *Context) error {
+			_, err := InjectE(ctx, serviceRef)
+			return err
+		})
+
+		var providerErr *ProviderError
+		if !errors.As(err, &providerErr) {
+			t.Errorf("%s: expected *ProviderError, got %v", name, err)
+		}
+	}
+}
+
+func TestValueBinding(t *testing.T) {
+	t.Parallel()
+
+	type Repository interface{ Find() string }
+
+	constructed := false
+	repositoryRef := Provide(func(ctx *Context) Repository {
+		constructed = true
+		return nil
+	})
+	fake := fakeRepository("fake")
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, repositoryRef).Find()
+	}, ProvideOptions[string]{Providers: []any{Value[Repository](repositoryRef, fake)}})
+
+	result := RunInContainer(NewContainer(), func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	if result != "fake" {
+		t.Errorf("expected 'fake', got '%s'", result)
+	}
+	if constructed {
+		t.Error("expected the overridden factory not to run")
+	}
+}
+
+type fakeRepository string
+
+func (r fakeRepository) Find() string { return string(r) }
+
+func TestValueTakesNoPartInCycleDetection(t *testing.T) {
+	t.Parallel()
+
+	var nodeRef *Ref[string]
+	nodeRef = Provide(func(ctx *Context) string {
+		return "node:" + Inject(ctx, nodeRef)
+	}, ProvideOptions[string]{Mode: ModeStandalone})
+	// The value replaces nodeRef in its own subtree, so nodeRef injecting
+	// itself is no longer a cycle
+	nodeRef.providers = []any{Value(nodeRef, "leaf")}
+
+	result := RunInContainer(NewContainer(), func(ctx *Context) string {
+		return Inject(ctx, nodeRef)
+	})
+
+	if result != "node:leaf" {
+		t.Errorf("expected 'node:leaf', got '%s'", result)
+	}
+}
+
+func TestProvideValue(t *testing.T) {
+	t.Parallel()
+
+	type Config struct{ Env string }
+
+	production := &Config{Env: "production"}
+	configRef := ProvideValue(production, ProvideOptions[*Config]{Name: "Config"})
+	testConfigRef := ProvideValue(&Config{Env: "test"}, ProvideOptions[*Config]{Overrides: configRef})
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, configRef).Env
+	}, ProvideOptions[string]{Providers: []any{testConfigRef}})
+
+	container := NewContainer()
+	config, env := RunInContainer(container, func(ctx *Context) *Config {
+		return Inject(ctx, configRef)
+	}), RunInContainer(container, func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	if config != production {
+		t.Errorf("expected the provided value, got %+v", config)
+	}
+	if env != "test" {
+		t.Errorf("expected 'test', got '%s'", env)
+	}
+	if configRef.Name() != "Config" || !strings.Contains(configRef.String(), "binding_test.go:") {
+		t.Errorf("expected the value ref to be described like any other, got '%s'", configRef)
+	}
+}
@@ -0,0 +1,297 @@
+// Command iocstub generates fakes for interfaces provided through go-ioc refs,
+// for use with ioctest.Stub.
+//
+// Usage, from a go:generate directive in the package declaring the interfaces:
+//
+//	//go:generate go run github.com/MunMunMiao/go-ioc/cmd/iocstub -type OrderRepository,Clock
+//
+// The fakes are written by default to ioc_stubs.go in a package named after
+// the declaring one with a "test" suffix, like shoptest for shop, in a
+// subdirectory of the same name. Tests of any package that stub the
+// interfaces import it for its side effects: the fakes register themselves
+// with ioctest.RegisterFake. An -output file in the package directory itself
+// adds the fakes to the declaring package instead, which only its own tests
+// can use if the file name ends in _test.go.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("iocstub: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of interface type names; required")
+	output := flag.String("output", "", "output file name, relative to the package directory; default <pkg>test/ioc_stubs.go")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: iocstub -type T[,T...] [-output file] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	path, src, err := generate(dir, *output, strings.Split(*typeNames, ","))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the path and source of the file holding the fakes for the
+// named interfaces of the package in dir. output is relative to dir; if it is
+// empty, the fakes go to the default package.
+func generate(dir, output string, typeNames []string) (string, []byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if output == "" {
+		output = filepath.Join(pkg.Name()+"test", "ioc_stubs.go")
+	}
+
+	g := &generator{pkg: pkg, name: pkg.Name(), local: true, imports: make(map[string]string)}
+	if outDir := filepath.Dir(output); outDir != "." {
+		g.local = false
+		g.name = filepath.Base(outDir)
+		if !token.IsIdentifier(g.name) {
+			return "", nil, fmt.Errorf("cannot name a package after the output directory %s", outDir)
+		}
+	}
+	g.imports["github.com/MunMunMiao/go-ioc/ioctest"] = "ioctest"
+	for _, name := range typeNames {
+		if err := g.stub(strings.TrimSpace(name)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by iocstub. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", g.name)
+	src.WriteString("import (\n")
+	// Standard library imports come first, in a group of their own
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if std := isStandard(paths[i]); std != isStandard(paths[j]) {
+			return std
+		}
+		return paths[i] < paths[j]
+	})
+	for i, path := range paths {
+		if i > 0 && isStandard(paths[i-1]) && !isStandard(path) {
+			src.WriteString("\n")
+		}
+		name := g.imports[path]
+		if name == importName(path) {
+			fmt.Fprintf(&src, "\t%s\n", strconv.Quote(path))
+		} else {
+			fmt.Fprintf(&src, "\t%s %s\n", name, strconv.Quote(path))
+		}
+	}
+	src.WriteString(")\n")
+	src.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return "", nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return filepath.Join(dir, output), formatted, nil
+}
+
+// loadPackage type-checks the non-test files of the package in dir
+func loadPackage(dir string) (*types.Package, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	// go/build does not know the import paths of module packages
+	list := exec.Command("go", "list", "-f", "{{.ImportPath}}", ".")
+	list.Dir = dir
+	importPath, err := list.Output()
+	if err != nil {
+		return nil, fmt.Errorf("finding the import path of %s: %v", dir, err)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range buildPkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(strings.TrimSpace(string(importPath)), fset, files, nil)
+}
+
+type generator struct {
+	pkg *types.Package
+	// name is the package of the generated file, and local is set if it is
+	// pkg itself. Otherwise it refers to the declarations of pkg through an
+	// import.
+	name  string
+	local bool
+	// imports maps the import paths used by the fakes to their names
+	imports map[string]string
+	buf     bytes.Buffer
+}
+
+// stub generates the fake of the named interface
+func (g *generator) stub(name string) error {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("%s is not a type in package %s", name, g.pkg.Name())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return fmt.Errorf("%s is not a named type", name)
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return fmt.Errorf("%s is not an interface", name)
+	}
+	if named.TypeParams().Len() > 0 {
+		return fmt.Errorf("%s is generic, which iocstub does not support", name)
+	}
+	if !iface.IsMethodSet() {
+		return fmt.Errorf("%s is a constraint, not an interface", name)
+	}
+
+	stub := lowerFirst(name) + "Stub"
+	fmt.Fprintf(&g.buf, "\nfunc init() {\n")
+	fmt.Fprintf(&g.buf, "\tioctest.RegisterFake(func(r *ioctest.Recorder) %s {\n\t\treturn &%s{recorder: r}\n\t})\n}\n", g.typeString(named), stub)
+	fmt.Fprintf(&g.buf, "\n// %s is a fake %s recording its calls\n", stub, name)
+	fmt.Fprintf(&g.buf, "type %s struct {\n\trecorder *ioctest.Recorder\n}\n", stub)
+
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		if !method.Exported() && (method.Pkg() != g.pkg || !g.local) {
+			return fmt.Errorf("%s has the unexported method %s, which a fake in another package cannot implement", name, method.Name())
+		}
+		g.method(stub, method)
+	}
+	return nil
+}
+
+// method generates a method of the fake that records its arguments and
+// returns the configured results
+func (g *generator) method(stub string, method *types.Func) {
+	sig := method.Type().(*types.Signature)
+
+	var params, args []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		typ := g.typeString(sig.Params().At(i).Type())
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			typ = "..." + g.typeString(sig.Params().At(i).Type().(*types.Slice).Elem())
+		}
+		params = append(params, fmt.Sprintf("a%d %s", i, typ))
+		args = append(args, fmt.Sprintf("a%d", i))
+	}
+
+	var results, values []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		typ := g.typeString(sig.Results().At(i).Type())
+		results = append(results, typ)
+		values = append(values, fmt.Sprintf("ioctest.Result[%s](results, %d)", typ, i))
+	}
+
+	record := fmt.Sprintf("s.recorder.Record(%s", strconv.Quote(method.Name()))
+	for _, arg := range args {
+		record += ", " + arg
+	}
+	record += ")"
+
+	fmt.Fprintf(&g.buf, "\nfunc (s *%s) %s(%s)", stub, method.Name(), strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+		fmt.Fprintf(&g.buf, " {\n\t%s\n}\n", record)
+		return
+	case 1:
+		fmt.Fprintf(&g.buf, " %s {\n", results[0])
+	default:
+		fmt.Fprintf(&g.buf, " (%s) {\n", strings.Join(results, ", "))
+	}
+	fmt.Fprintf(&g.buf, "\tresults := %s\n\treturn %s\n}\n", record, strings.Join(values, ", "))
+}
+
+// typeString formats typ as seen from the generated file, adding the imports
+// it needs
+func (g *generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == g.pkg && g.local {
+			return ""
+		}
+		if name, ok := g.imports[pkg.Path()]; ok {
+			return name
+		}
+		name := pkg.Name()
+		for n := 2; g.nameTaken(name); n++ {
+			name = fmt.Sprintf("%s%d", pkg.Name(), n)
+		}
+		g.imports[pkg.Path()] = name
+		return name
+	})
+}
+
+func (g *generator) nameTaken(name string) bool {
+	for _, taken := range g.imports {
+		if taken == name {
+			return true
+		}
+	}
+	return false
+}
+
+// importName guesses the package name of an import path, which is enough to
+// decide whether the import needs a name
+func importName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// isStandard reports whether path belongs to the standard library, whose
+// import paths have no dot in their first element
+func isStandard(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
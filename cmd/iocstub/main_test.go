@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+const golden = "testdata/shop/ioc_stubs.go.golden"
+
+func TestGenerateMatchesGolden(t *testing.T) {
+	path, src, err := generate("testdata/shop", "", []string{"OrderRepository", "Mailer"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := filepath.Join("testdata", "shop", "shoptest", "ioc_stubs.go"); path != expected {
+		t.Errorf("expected the fakes to go to %s, got %s", expected, path)
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != string(expected) {
+		t.Errorf("generated code differs from %s, run go test -update to see the changes:\n%s", golden, src)
+	}
+}
+
+// packageImporter resolves the package under test itself, which is not in
+// the module, and leaves the others to the source importer
+type packageImporter struct {
+	pkg      *types.Package
+	fallback types.Importer
+}
+
+func (i packageImporter) Import(path string) (*types.Package, error) {
+	if path == i.pkg.Path() {
+		return i.pkg, nil
+	}
+	return i.fallback.Import(path)
+}
+
+func TestGeneratedCodeCompiles(t *testing.T) {
+	for _, output := range []string{"", "ioc_stubs_test.go"} {
+		_, src, err := generate("testdata/shop", output, []string{"OrderRepository", "Mailer"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		fset := token.NewFileSet()
+		parse := func(name string, content any) *ast.File {
+			file, err := parser.ParseFile(fset, name, content, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return file
+		}
+		shop := parse(filepath.Join("testdata", "shop", "shop.go"), nil)
+		stubs := parse(filepath.Join("testdata", "shop", output), src)
+
+		conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+		if output != "" {
+			if _, err := conf.Check("shop", fset, []*ast.File{shop, stubs}, nil); err != nil {
+				t.Errorf("generated code does not compile in the package: %v", err)
+			}
+			continue
+		}
+		pkg, err := conf.Check("github.com/MunMunMiao/go-ioc/cmd/iocstub/testdata/shop", fset, []*ast.File{shop}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.Importer = packageImporter{pkg: pkg, fallback: conf.Importer}
+		if _, err := conf.Check("shoptest", fset, []*ast.File{stubs}, nil); err != nil {
+			t.Errorf("generated package does not compile: %v", err)
+		}
+	}
+}
+
+func TestGeneratedPackageIsUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "ioctest", "internal", "shop")
+	path, src, err := generate(dir, "", []string{"Mailer"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	committed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != string(committed) {
+		t.Errorf("%s is stale, run go generate in %s", path, dir)
+	}
+}
+
+func TestGenerateRejectsNonInterfaces(t *testing.T) {
+	for _, name := range []string{"Order", "Missing"} {
+		_, _, err := generate("testdata/shop", "", []string{name})
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("expected an error naming %s, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Package shop declares interfaces to generate fakes for
+package shop
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type Order struct {
+	ID string
+}
+
+type OrderRepository interface {
+	Save(ctx context.Context, order *Order) error
+	FindByID(ctx context.Context, id string) (*Order, error)
+	FindByCustomer(customerID string, since ...time.Time) ([]*Order, error)
+}
+
+type Mailer interface {
+	io.Closer
+	Send(to string, body []byte)
+}
@@ -0,0 +1,386 @@
+// Package analyzer implements iocvet, a go/analysis pass that checks go-ioc
+// wiring at compile time.
+//
+// Refs are package-level variables initialized with ioc.Provide or
+// ioc.ProvideE and their dependencies are ioc.Inject calls inside the
+// factory, so most of the graph can be recovered from the syntax tree. The
+// analyzer reports:
+//
+//   - cycles between package-level refs assigned outside their declaration,
+//     ignoring InjectLazy, which is meant for back-references
+//   - Inject calls that use a *ioc.Context captured from outside the factory
+//   - Inject calls made from goroutines started inside a factory
+//   - ProvideOptions.Overrides targets whose type parameter does not match
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const iocPath = "github.com/MunMunMiao/go-ioc"
+
+const doc = `check go-ioc wiring
+
+iocvet reports dependency cycles between package-level refs, Inject calls
+that use a context captured from outside their factory, Inject calls made
+from goroutines spawned by a factory, and ProvideOptions.Overrides targets
+whose type parameter does not match the overriding ref.`
+
+// Analyzer is the iocvet analysis pass
+var Analyzer = &analysis.Analyzer{
+	Name:     "iocvet",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// refDecl is a package-level ref and the dependencies its factory injects
+type refDecl struct {
+	obj  *types.Var
+	deps []dependency
+}
+
+// dependency is an edge from a factory to a package-level ref
+type dependency struct {
+	ref  *types.Var
+	call *ast.CallExpr
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	funcs := make(map[*types.Func]*ast.FuncDecl)
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				if obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func); ok {
+					funcs[obj] = fn
+				}
+			}
+		}
+	}
+
+	refs := packageRefs(pass)
+	isRef := make(map[*types.Var]bool)
+	for _, ref := range refs {
+		isRef[ref.obj] = true
+	}
+
+	filter := []ast.Node{(*ast.CallExpr)(nil), (*ast.CompositeLit)(nil)}
+	inspect.Preorder(filter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if !isProvideCall(pass, n) || len(n.Args) == 0 {
+				return
+			}
+			factory := factoryNode(pass, n.Args[0], funcs)
+			if factory == nil {
+				return
+			}
+			deps := checkFactory(pass, factory, isRef)
+			if ref := refs[n]; ref != nil {
+				ref.deps = append(ref.deps, deps...)
+			}
+		case *ast.CompositeLit:
+			checkOverrides(pass, n)
+		}
+	})
+
+	reportCycles(pass, refs)
+	return nil, nil
+}
+
+// packageRefs collects the package-level refs keyed by their Provide call.
+// Refs that depend on each other through their initializers are rejected by
+// the compiler as an initialization cycle, so refs assigned from functions
+// such as init are collected as well.
+func packageRefs(pass *analysis.Pass) map[*ast.CallExpr]*refDecl {
+	refs := make(map[*ast.CallExpr]*refDecl)
+	byObj := make(map[*types.Var]*refDecl)
+	add := func(obj types.Object, value ast.Expr) {
+		v, ok := obj.(*types.Var)
+		if !ok || v.Parent() != pass.Pkg.Scope() {
+			return
+		}
+		call, ok := ast.Unparen(value).(*ast.CallExpr)
+		if !ok || !isProvideCall(pass, call) {
+			return
+		}
+		if byObj[v] == nil {
+			byObj[v] = &refDecl{obj: v}
+		}
+		refs[call] = byObj[v]
+	}
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ValueSpec:
+				if len(n.Names) == len(n.Values) {
+					for i, name := range n.Names {
+						add(pass.TypesInfo.Defs[name], n.Values[i])
+					}
+				}
+			case *ast.AssignStmt:
+				if n.Tok == token.ASSIGN && len(n.Lhs) == len(n.Rhs) {
+					for i, lhs := range n.Lhs {
+						if id, ok := ast.Unparen(lhs).(*ast.Ident); ok {
+							add(pass.TypesInfo.Uses[id], n.Rhs[i])
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// factoryNode returns the function literal or declaration passed as a factory
+func factoryNode(pass *analysis.Pass, expr ast.Expr, funcs map[*types.Func]*ast.FuncDecl) ast.Node {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.FuncLit:
+		return expr
+	case *ast.Ident:
+		if fn, ok := pass.TypesInfo.Uses[expr].(*types.Func); ok {
+			if decl := funcs[fn]; decl != nil {
+				return decl
+			}
+		}
+	}
+	return nil
+}
+
+// checkFactory reports misuse of the injection context inside a factory and
+// returns the package-level refs it injects
+func checkFactory(pass *analysis.Pass, factory ast.Node, isRef map[*types.Var]bool) []dependency {
+	var body *ast.BlockStmt
+	switch factory := factory.(type) {
+	case *ast.FuncLit:
+		body = factory.Body
+	case *ast.FuncDecl:
+		body = factory.Body
+	}
+
+	var deps []dependency
+	var walk func(node ast.Node, inGoroutine bool)
+	walk = func(node ast.Node, inGoroutine bool) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GoStmt:
+				walk(n.Call, true)
+				return false
+			case *ast.CallExpr:
+				// Nested factories are checked on their own
+				if isProvideCall(pass, n) {
+					for _, arg := range n.Args[min(1, len(n.Args)):] {
+						walk(arg, inGoroutine)
+					}
+					return false
+				}
+				if !isInjectCall(pass, n) || len(n.Args) < 2 {
+					return true
+				}
+				name := calleeName(pass, n)
+				switch {
+				case inGoroutine:
+					pass.Reportf(n.Pos(), "%s called in a goroutine started by a factory; inject before starting the goroutine", name)
+				case capturedContext(pass, factory, n.Args[0]):
+					pass.Reportf(n.Args[0].Pos(), "%s uses a *ioc.Context captured from outside the factory; use the factory's ctx parameter", name)
+				}
+				// Lazy injections resolve after the factory returns, so
+				// they can legitimately point back at it
+				if name == "ioc.InjectLazy" {
+					return true
+				}
+				if id, ok := ast.Unparen(n.Args[1]).(*ast.Ident); ok {
+					if obj, ok := pass.TypesInfo.Uses[id].(*types.Var); ok && isRef[obj] {
+						deps = append(deps, dependency{ref: obj, call: n})
+					}
+				}
+			}
+			return true
+		})
+	}
+	walk(body, false)
+	return deps
+}
+
+// capturedContext reports whether expr is rooted at a variable declared
+// outside the factory
+func capturedContext(pass *analysis.Pass, factory ast.Node, expr ast.Expr) bool {
+	id := rootIdent(expr)
+	if id == nil {
+		return false
+	}
+	obj, ok := pass.TypesInfo.Uses[id].(*types.Var)
+	if !ok {
+		return false
+	}
+	return obj.Pos() < factory.Pos() || obj.Pos() >= factory.End()
+}
+
+// rootIdent returns the identifier an expression such as ctx, ctx.NewScope()
+// or s.ctx is derived from
+func rootIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.CallExpr:
+			sel, ok := ast.Unparen(e.Fun).(*ast.SelectorExpr)
+			if !ok {
+				return nil
+			}
+			expr = sel.X
+		case *ast.StarExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// checkOverrides reports ProvideOptions[T]{Overrides: ref} where ref is not a
+// *ioc.Ref[T]
+func checkOverrides(pass *analysis.Pass, lit *ast.CompositeLit) {
+	want, ok := typeArg(pass.TypesInfo.TypeOf(lit), "ProvideOptions")
+	if !ok {
+		return
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if key, ok := kv.Key.(*ast.Ident); !ok || key.Name != "Overrides" {
+			continue
+		}
+		tv := pass.TypesInfo.Types[kv.Value]
+		if tv.IsNil() || types.IsInterface(tv.Type) {
+			continue
+		}
+		ptr, ok := types.Unalias(tv.Type).(*types.Pointer)
+		if !ok {
+			pass.Reportf(kv.Value.Pos(), "Overrides target must be a *ioc.Ref[%s], got %s", typeString(pass, want), typeString(pass, tv.Type))
+			continue
+		}
+		got, ok := typeArg(ptr.Elem(), "Ref")
+		if !ok {
+			pass.Reportf(kv.Value.Pos(), "Overrides target must be a *ioc.Ref[%s], got %s", typeString(pass, want), typeString(pass, tv.Type))
+			continue
+		}
+		if !types.Identical(got, want) {
+			pass.Reportf(kv.Value.Pos(), "Overrides target is a *ioc.Ref[%s] but the overriding ref provides %s", typeString(pass, got), typeString(pass, want))
+		}
+	}
+}
+
+// reportCycles reports each cycle between package-level refs once
+func reportCycles(pass *analysis.Pass, refs map[*ast.CallExpr]*refDecl) {
+	byObj := make(map[*types.Var]*refDecl)
+	var order []*refDecl
+	for _, ref := range refs {
+		if byObj[ref.obj] == nil {
+			byObj[ref.obj] = ref
+			order = append(order, ref)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].obj.Pos() < order[j].obj.Pos() })
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*refDecl]int, len(order))
+	var stack []*refDecl
+	var visit func(ref *refDecl)
+	visit = func(ref *refDecl) {
+		state[ref] = visiting
+		stack = append(stack, ref)
+		for _, dep := range ref.deps {
+			next := byObj[dep.ref]
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				reportCycle(pass, stack, next, dep)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[ref] = done
+	}
+	for _, ref := range order {
+		if state[ref] == unvisited {
+			visit(ref)
+		}
+	}
+}
+
+// reportCycle reports the cycle closed by dep at the Inject call that closes it
+func reportCycle(pass *analysis.Pass, stack []*refDecl, start *refDecl, dep dependency) {
+	i := len(stack) - 1
+	for stack[i] != start {
+		i--
+	}
+	var names []string
+	for _, ref := range stack[i:] {
+		names = append(names, ref.obj.Name())
+	}
+	names = append(names, start.obj.Name())
+	pass.Reportf(dep.call.Pos(), "circular dependency: %s", strings.Join(names, " -> "))
+}
+
+func isProvideCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	name := iocFunc(pass, call)
+	return name == "Provide" || name == "ProvideE"
+}
+
+func isInjectCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	return strings.HasPrefix(iocFunc(pass, call), "Inject")
+}
+
+// iocFunc returns the name of the go-ioc package function called, if any
+func iocFunc(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != iocPath {
+		return ""
+	}
+	if fn.Type().(*types.Signature).Recv() != nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+func calleeName(pass *analysis.Pass, call *ast.CallExpr) string {
+	return "ioc." + iocFunc(pass, call)
+}
+
+// typeArg returns the type argument of an instantiated go-ioc generic type
+func typeArg(t types.Type, name string) (types.Type, bool) {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.TypeArgs().Len() != 1 {
+		return nil, false
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != iocPath || obj.Name() != name {
+		return nil, false
+	}
+	return named.TypeArgs().At(0), true
+}
+
+func typeString(pass *analysis.Pass, t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(pass.Pkg))
+}
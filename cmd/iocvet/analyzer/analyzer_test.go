@@ -0,0 +1,25 @@
+package analyzer_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/MunMunMiao/go-ioc/cmd/iocvet/analyzer"
+)
+
+func TestCycles(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Analyzer, "./cycles")
+}
+
+func TestCapturedContext(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Analyzer, "./captured")
+}
+
+func TestInjectInGoroutine(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Analyzer, "./goroutine")
+}
+
+func TestOverridesType(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Analyzer, "./overrides")
+}
@@ -0,0 +1,33 @@
+package captured
+
+import ioc "github.com/MunMunMiao/go-ioc"
+
+type Config struct{}
+type Service struct{ config *Config }
+
+var ConfigRef = ioc.Provide(func(ctx *ioc.Context) *Config { return &Config{} })
+
+var rootCtx *ioc.Context
+
+var ServiceRef = ioc.Provide(func(ctx *ioc.Context) *Service {
+	return &Service{config: ioc.Inject(rootCtx, ConfigRef)} // want `ioc.Inject uses a \*ioc.Context captured from outside the factory`
+})
+
+func register(outer *ioc.Context) *ioc.Ref[*Service] {
+	return ioc.Provide(func(ctx *ioc.Context) *Service {
+		return &Service{config: ioc.Inject(outer.NewScope(), ConfigRef)} // want `ioc.Inject uses a \*ioc.Context captured`
+	})
+}
+
+var ScopedRef = ioc.Provide(func(ctx *ioc.Context) *Service {
+	scope := ctx.NewScope()
+	get := func() *Config { return ioc.Inject(scope, ConfigRef) }
+	return &Service{config: get()}
+})
+
+func main() {
+	ioc.RunInInjectionContext(func(ctx *ioc.Context) any {
+		return ioc.Inject(ctx, ServiceRef)
+	})
+	_ = register
+}
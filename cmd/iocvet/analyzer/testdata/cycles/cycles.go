@@ -0,0 +1,71 @@
+package cycles
+
+import ioc "github.com/MunMunMiao/go-ioc"
+
+type A struct{ b *B }
+type B struct{ c *C }
+type C struct{ a *A }
+
+// Initializers that refer to each other do not compile, so cyclic refs are
+// assigned from init
+var (
+	ARef *ioc.Ref[*A]
+	BRef *ioc.Ref[*B]
+	CRef *ioc.Ref[*C]
+)
+
+func init() {
+	ARef = ioc.Provide(func(ctx *ioc.Context) *A {
+		return &A{b: ioc.Inject(ctx, BRef)}
+	})
+	BRef = ioc.Provide(func(ctx *ioc.Context) *B {
+		return &B{c: ioc.Inject(ctx, CRef)}
+	})
+	CRef = ioc.Provide(func(ctx *ioc.Context) *C {
+		return &C{a: ioc.Inject(ctx, ARef)} // want `circular dependency: ARef -> BRef -> CRef -> ARef`
+	})
+}
+
+type Self struct{}
+
+var SelfRef *ioc.Ref[*Self]
+
+func init() {
+	SelfRef = ioc.ProvideE(newSelf)
+}
+
+func newSelf(ctx *ioc.Context) (*Self, error) {
+	_, err := ioc.InjectE(ctx, SelfRef) // want `circular dependency: SelfRef -> SelfRef`
+	return &Self{}, err
+}
+
+type Parent struct{ child *ioc.Lazy[*Child] }
+type Child struct{ parent *Parent }
+
+// Lazy injections are resolved after the factory returns
+var (
+	ParentRef *ioc.Ref[*Parent]
+	ChildRef  *ioc.Ref[*Child]
+)
+
+func init() {
+	ParentRef = ioc.Provide(func(ctx *ioc.Context) *Parent {
+		return &Parent{child: ioc.InjectLazy(ctx, ChildRef)}
+	})
+	ChildRef = ioc.Provide(func(ctx *ioc.Context) *Child {
+		return &Child{parent: ioc.Inject(ctx, ParentRef)}
+	})
+}
+
+// Diamonds share dependencies without forming a cycle
+type Leaf struct{}
+
+var LeafRef = ioc.Provide(func(ctx *ioc.Context) *Leaf { return &Leaf{} })
+
+var LeftRef = ioc.Provide(func(ctx *ioc.Context) *Leaf { return ioc.Inject(ctx, LeafRef) })
+
+var RightRef = ioc.Provide(func(ctx *ioc.Context) *Leaf { return ioc.Inject(ctx, LeafRef) })
+
+var TopRef = ioc.Provide(func(ctx *ioc.Context) []*Leaf {
+	return []*Leaf{ioc.Inject(ctx, LeftRef), ioc.Inject(ctx, RightRef)}
+})
@@ -0,0 +1,7 @@
+module example.com/testdata
+
+go 1.22
+
+require github.com/MunMunMiao/go-ioc v0.0.0
+
+replace github.com/MunMunMiao/go-ioc => ../../../..
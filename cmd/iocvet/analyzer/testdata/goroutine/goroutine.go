@@ -0,0 +1,34 @@
+package goroutine
+
+import ioc "github.com/MunMunMiao/go-ioc"
+
+type Config struct{}
+type Worker struct{ config chan *Config }
+
+var ConfigRef = ioc.Provide(func(ctx *ioc.Context) *Config { return &Config{} })
+
+var WorkerRef = ioc.Provide(func(ctx *ioc.Context) *Worker {
+	w := &Worker{config: make(chan *Config, 1)}
+	go func() {
+		w.config <- ioc.Inject(ctx, ConfigRef) // want `ioc.Inject called in a goroutine started by a factory`
+	}()
+	return w
+})
+
+var EagerWorkerRef = ioc.Provide(func(ctx *ioc.Context) *Worker {
+	w := &Worker{config: make(chan *Config, 1)}
+	config := ioc.Inject(ctx, ConfigRef)
+	go func() {
+		w.config <- config
+	}()
+	return w
+})
+
+func serve() {
+	ioc.RunInInjectionContext(func(ctx *ioc.Context) any {
+		go func() {
+			_ = ioc.Inject(ctx, ConfigRef)
+		}()
+		return nil
+	})
+}
@@ -0,0 +1,28 @@
+package overrides
+
+import ioc "github.com/MunMunMiao/go-ioc"
+
+type Config struct{}
+type OtherConfig struct{}
+
+var ConfigRef = ioc.Provide(func(ctx *ioc.Context) *Config { return &Config{} })
+
+var OtherConfigRef = ioc.Provide(func(ctx *ioc.Context) *OtherConfig { return &OtherConfig{} })
+
+var TestConfigRef = ioc.Provide(func(ctx *ioc.Context) *Config {
+	return &Config{}
+}, ioc.ProvideOptions[*Config]{Overrides: ConfigRef})
+
+var WrongConfigRef = ioc.Provide(func(ctx *ioc.Context) *Config {
+	return &Config{}
+}, ioc.ProvideOptions[*Config]{Overrides: OtherConfigRef}) // want `Overrides target is a \*ioc.Ref\[\*OtherConfig\] but the overriding ref provides \*Config`
+
+var NotARefRef = ioc.Provide(func(ctx *ioc.Context) *Config {
+	return &Config{}
+}, ioc.ProvideOptions[*Config]{Overrides: &Config{}}) // want `Overrides target must be a \*ioc.Ref\[\*Config\], got \*Config`
+
+var anyRef any = ConfigRef
+
+var DynamicRef = ioc.Provide(func(ctx *ioc.Context) *Config {
+	return &Config{}
+}, ioc.ProvideOptions[*Config]{Overrides: anyRef})
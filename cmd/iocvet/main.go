@@ -0,0 +1,21 @@
+// Command iocvet checks go-ioc wiring without running it.
+//
+// Usage:
+//
+//	go run github.com/MunMunMiao/go-ioc/cmd/iocvet ./...
+//
+// It can also be used as a vet tool:
+//
+//	go build -o iocvet github.com/MunMunMiao/go-ioc/cmd/iocvet
+//	go vet -vettool=$(pwd)/iocvet ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/MunMunMiao/go-ioc/cmd/iocvet/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
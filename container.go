@@ -0,0 +1,155 @@
+package ioc
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Container owns a set of global singletons and their lifecycle. The package
+// level functions use a default container shared by the whole process.
+type Container struct {
+	singletons *singletons
+	// graph is nil until RecordGraph is called
+	graph atomic.Pointer[graphRecorder]
+	// roots are the refs checked by Validate
+	roots []refMarker
+	// bindings holds the providers registered with Bind. It is replaced, never
+	// changed, by Bind, so it can be read without holding mu.
+	bindings *Context
+	mu       sync.RWMutex
+	// lifecycleMu serializes Start and Stop
+	lifecycleMu sync.Mutex
+}
+
+var defaultContainer = NewContainer()
+
+// NewContainer creates a container with its own, empty singleton cache
+func NewContainer() *Container {
+	return &Container{
+		singletons: newSingletons(nil),
+	}
+}
+
+// singletons caches global instances, guarded by the mu of their container
+type singletons struct {
+	instances map[any]any
+	calls     map[any]*call
+	// lifecycles holds resolved instances with hooks in creation order, which
+	// puts every instance after the dependencies it injected
+	lifecycles []*lifecycle
+	// disposer, when set, disposes of the instances instead of running their
+	// lifecycle hooks
+	disposer *disposer
+}
+
+func newSingletons(disposer *disposer) *singletons {
+	return &singletons{
+		instances: make(map[any]any),
+		calls:     make(map[any]*call),
+		disposer:  disposer,
+	}
+}
+
+// ContextOption configures a root injection context
+type ContextOption func(ctx *Context)
+
+// WithProviders registers providers on the root context, so they override
+// refs for everything resolved from it. Global refs resolved from such a
+// context are cached in it instead of the container, so the container's
+// singletons never see the overrides; concurrent callers still share a single
+// construction of each. They are disposed of when the context is closed and
+// their lifecycle hooks are not run. It panics with a
+// *ProviderError if a provider is invalid.
+func WithProviders(providers ...any) ContextOption {
+	return func(ctx *Context) {
+		ctx.singletons = newSingletons(ctx.disposer)
+		for _, provider := range providers {
+			if err := registerProvider(ctx, provider); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// Bind registers bindings for every context of the default container
+func Bind(bindings ...Binding) {
+	defaultContainer.Bind(bindings...)
+}
+
+// Bind registers bindings for every context of the container, below the
+// providers of the contexts themselves. Unlike WithProviders, it leaves global
+// refs in the container, so a token bound to a global ref resolves to that
+// ref's singleton, which Start, Stop and Reset manage as usual. Bindings are
+// meant to be registered once, before anything is resolved: instances
+// already cached are not affected. It panics with a *ProviderError if a
+// binding is invalid, in which case none of bindings is registered.
+func (c *Container) Bind(bindings ...Binding) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bound := createContext(c, nil)
+	if c.bindings != nil {
+		for key, provider := range c.bindings.localProviders {
+			bound.localProviders[key] = provider
+		}
+	}
+	for _, binding := range bindings {
+		if binding == nil {
+			panic(&ProviderError{Reason: "Bind called with a nil binding"})
+		}
+		if err := binding.register(bound); err != nil {
+			panic(err)
+		}
+	}
+	c.bindings = bound
+}
+
+// bound returns the context holding the bindings of the container, or nil
+// if there are none
+func (c *Container) bound() *Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.bindings
+}
+
+// NewContext creates a root injection context of the container. Close it to
+// dispose of the instances it created.
+func (c *Container) NewContext(opts ...ContextOption) *Context {
+	ctx := createContext(c, nil)
+	for _, opt := range opts {
+		opt(ctx)
+	}
+	return ctx
+}
+
+// Container returns the container ctx belongs to
+func (ctx *Context) Container() *Container {
+	return ctx.container
+}
+
+// RunInContainer executes a function within an injection context of the
+// given container
+func RunInContainer[T any](c *Container, fn func(ctx *Context) T, opts ...ContextOption) T {
+	ctx := c.NewContext(opts...)
+	return fn(ctx)
+}
+
+// Reset clears all cached global instances of the container. Bindings are
+// kept.
+func (c *Container) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.singletons = newSingletons(nil)
+	if c.graph.Load() != nil {
+		c.graph.Store(newGraphRecorder())
+	}
+}
+
+func (c *Container) globals() *singletons {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.singletons
+}
+
+func (c *Container) recorder() *graphRecorder {
+	return c.graph.Load()
+}
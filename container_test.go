@@ -0,0 +1,273 @@
+package ioc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestContainersHaveIsolatedSingletons(t *testing.T) {
+	t.Parallel()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&counter, 1)
+	})
+
+	first := NewContainer()
+	second := NewContainer()
+
+	a1 := RunInContainer(first, func(ctx *Context) int32 { return Inject(ctx, ref) })
+	a2 := RunInContainer(first, func(ctx *Context) int32 { return Inject(ctx, ref) })
+	b := RunInContainer(second, func(ctx *Context) int32 { return Inject(ctx, ref) })
+
+	if a1 != a2 {
+		t.Errorf("expected same instance within a container, got %d and %d", a1, a2)
+	}
+	if a1 == b {
+		t.Errorf("expected different instances across containers, got %d", b)
+	}
+	if counter != 2 {
+		t.Errorf("expected counter to be 2, got %d", counter)
+	}
+}
+
+func TestContainerResetDoesNotAffectOtherContainers(t *testing.T) {
+	t.Parallel()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&counter, 1)
+	})
+
+	first := NewContainer()
+	second := NewContainer()
+
+	ctx := second.NewContext()
+	before := Inject(ctx, ref)
+	RunInContainer(first, func(ctx *Context) int32 { return Inject(ctx, ref) })
+
+	first.Reset()
+
+	if after := Inject(second.NewContext(), ref); after != before {
+		t.Errorf("expected %d to survive reset of another container, got %d", before, after)
+	}
+	if value := RunInContainer(first, func(ctx *Context) int32 { return Inject(ctx, ref) }); value != 3 {
+		t.Errorf("expected reset container to construct again, got %d", value)
+	}
+}
+
+func TestContainerLifecycleIsIsolated(t *testing.T) {
+	t.Parallel()
+
+	var stopped int32
+	ref := Provide(func(ctx *Context) string {
+		return "server"
+	}, ProvideOptions[string]{
+		OnStop: func(ctx context.Context, _ string) error {
+			atomic.AddInt32(&stopped, 1)
+			return nil
+		},
+	})
+
+	first := NewContainer()
+	second := NewContainer()
+	Inject(first.NewContext(), ref)
+	Inject(second.NewContext(), ref)
+
+	if err := first.StopAndReset(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stopped != 1 {
+		t.Errorf("expected only one instance to be stopped, got %d", stopped)
+	}
+}
+
+func TestRootProvidersOverrideWholeResolution(t *testing.T) {
+	t.Parallel()
+
+	type Config struct{ Env string }
+
+	configRef := Provide(func(ctx *Context) *Config { return &Config{Env: "production"} })
+	repositoryRef := Provide(func(ctx *Context) string {
+		return "repository:" + Inject(ctx, configRef).Env
+	})
+	serviceRef := Provide(func(ctx *Context) string {
+		return "service(" + Inject(ctx, repositoryRef) + ")"
+	}, ProvideOptions[string]{Mode: ModeStandalone})
+
+	container := NewContainer()
+	fake := RunInContainer(container, func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	}, WithProviders(Value(configRef, &Config{Env: "test"})))
+	production := RunInContainer(container, func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	if fake != "service(repository:test)" {
+		t.Errorf("expected the root override to reach every dependency, got '%s'", fake)
+	}
+	if production != "service(repository:production)" {
+		t.Errorf("expected the container singletons to be untouched, got '%s'", production)
+	}
+}
+
+func TestRootProvidersKeepGlobalsInContext(t *testing.T) {
+	t.Parallel()
+
+	var constructed int32
+	var closed []string
+	clockRef := Provide(func(ctx *Context) string { return "real" })
+	poolRef := Provide(func(ctx *Context) *closable {
+		atomic.AddInt32(&constructed, 1)
+		return &closable{name: "pool:" + Inject(ctx, clockRef), closed: &closed}
+	})
+
+	container := NewContainer()
+	ctx := container.NewContext(WithProviders(Value(clockRef, "fake")))
+	first := Inject(ctx, poolRef)
+	second := Inject(ctx.NewScope(), poolRef)
+
+	if first != second {
+		t.Error("expected globals to be shared within the overriding context")
+	}
+	if first.name != "pool:fake" {
+		t.Errorf("expected 'pool:fake', got '%s'", first.name)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(closed) != 1 || closed[0] != "pool:fake" {
+		t.Errorf("expected the context to dispose of its globals, got %v", closed)
+	}
+
+	if production := Inject(container.NewContext(), poolRef); production.name != "pool:real" {
+		t.Errorf("expected a separate container singleton, got '%s'", production.name)
+	}
+	if constructed != 2 {
+		t.Errorf("expected 2 constructions, got %d", constructed)
+	}
+}
+
+func TestRootProvidersShareConcurrentConstruction(t *testing.T) {
+	t.Parallel()
+
+	var constructed int32
+	var closed []string
+	clockRef := Provide(func(ctx *Context) string { return "real" })
+	configRef := Provide(func(ctx *Context) string { return "config:" + Inject(ctx, clockRef) })
+	poolRef := Provide(func(ctx *Context) *closable {
+		atomic.AddInt32(&constructed, 1)
+		return &closable{name: "pool:" + Inject(ctx, configRef), closed: &closed}
+	})
+
+	ctx := NewContainer().NewContext(WithProviders(Value(clockRef, "fake")))
+	defer ctx.Close()
+
+	var wg sync.WaitGroup
+	pools := make([]*closable, 20)
+	for i := range pools {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pools[i] = Inject(ctx, poolRef)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, pool := range pools {
+		if pool != pools[0] || pool.name != "pool:config:fake" {
+			t.Fatalf("expected every goroutine to get the same overridden pool, got %+v", pool)
+		}
+	}
+	if constructed != 1 {
+		t.Errorf("expected 1 construction, got %d", constructed)
+	}
+}
+
+func TestRootProvidersRejectInvalidProvider(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if _, ok := recover().(*ProviderError); !ok {
+			t.Error("expected NewContext to panic with *ProviderError")
+		}
+	}()
+	NewContainer().NewContext(WithProviders("not a ref"))
+}
+
+func TestBindKeepsGlobalsInContainer(t *testing.T) {
+	t.Parallel()
+
+	var constructed, started int32
+	storeRef := Token[string]("Store")
+	memoryRef := Provide(func(ctx *Context) string {
+		atomic.AddInt32(&constructed, 1)
+		return "memory"
+	}, ProvideOptions[string]{OnStart: func(ctx context.Context, instance string) error {
+		atomic.AddInt32(&started, 1)
+		return nil
+	}})
+
+	container := NewContainer()
+	container.Bind(Override(storeRef, memoryRef))
+	first := RunInContainer(container, func(ctx *Context) string { return Inject(ctx, storeRef) })
+	second := RunInContainer(container, func(ctx *Context) string { return Inject(ctx, storeRef) })
+
+	if first != "memory" || second != "memory" {
+		t.Errorf("expected the bound provider, got '%s' and '%s'", first, second)
+	}
+	if constructed != 1 {
+		t.Errorf("expected one singleton shared by every context, got %d constructions", constructed)
+	}
+	if err := container.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if started != 1 {
+		t.Errorf("expected Start to run the hook of the bound singleton, got %d", started)
+	}
+
+	container.Reset()
+	if value := RunInContainer(container, func(ctx *Context) string { return Inject(ctx, storeRef) }); value != "memory" || constructed != 2 {
+		t.Errorf("expected Reset to keep the bindings and drop the singleton, got '%s' after %d constructions", value, constructed)
+	}
+}
+
+func TestContextProvidersTakePrecedenceOverBind(t *testing.T) {
+	t.Parallel()
+
+	storeRef := Token[string]("Store")
+	numbers := NewGroup[string]("stores")
+
+	container := NewContainer()
+	container.Bind(Value(storeRef, "bound"), AddToGroup(numbers, storeRef))
+	ctx := container.NewContext(WithProviders(Value(storeRef, "local")))
+
+	if value := Inject(ctx, storeRef); value != "local" {
+		t.Errorf("expected the root provider to win, got '%s'", value)
+	}
+	if values := InjectAll(container.NewContext(), numbers); len(values) != 1 || values[0] != "bound" {
+		t.Errorf("expected the bound group member, got %v", values)
+	}
+}
+
+func TestBindRejectsInvalidBindings(t *testing.T) {
+	t.Parallel()
+
+	storeRef := Token[string]("Store")
+	container := NewContainer()
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*ProviderError); !ok {
+				t.Error("expected Bind to panic with *ProviderError")
+			}
+		}()
+		container.Bind(Value(storeRef, "bound"), Override(storeRef, nil))
+	}()
+
+	if _, ok := InjectOptional(container.NewContext(), storeRef); ok {
+		t.Error("expected a rejected Bind to register none of its bindings")
+	}
+}
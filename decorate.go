@@ -0,0 +1,137 @@
+package ioc
+
+// decoratorKey identifies the decorators of a ref in Context.localProviders,
+// where the ref itself is the key of its override
+type decoratorKey struct {
+	ref refMarker
+}
+
+// decoratorBinding decorates target for a context subtree
+type decoratorBinding[T any] struct {
+	target *Ref[T]
+	fn     func(ctx *Context, inner T) T
+}
+
+// register implements Binding
+func (b decoratorBinding[T]) register(ctx *Context) error {
+	if b.target == nil || b.fn == nil {
+		return &ProviderError{Reason: "Decorate called with a nil ref or decorator"}
+	}
+	key := decoratorKey{ref: b.target}
+	decorators, _ := ctx.localProviders[key].([]func(ctx *Context, inner T) T)
+	ctx.localProviders[key] = append(decorators, b.fn)
+	return nil
+}
+
+// Decorate wraps the instances of ref resolved in the subtree the binding is
+// registered in with fn, which receives the instance built so far. Like an
+// override, it makes global instances of ref resolved below it be cached in
+// the context it is registered in, so the container singleton stays
+// undecorated. Registered with Container.Bind, it applies in every context
+// of the container.
+func Decorate[T any](ref *Ref[T], fn func(ctx *Context, inner T) T) Binding {
+	return decoratorBinding[T]{target: ref, fn: fn}
+}
+
+// Decorate wraps every instance of the ref resolved from now on with fn, in
+// every context, including the instances of the providers it is overridden
+// with and the values it is bound to. Register decorators from init
+// functions or package-level variables, before the ref is first resolved.
+func (r *Ref[T]) Decorate(fn func(ctx *Context, inner T) T) {
+	r.decoratorsMu.Lock()
+	defer r.decoratorsMu.Unlock()
+	r.decorators = append(r.decorators, fn)
+}
+
+// decorate applies the decorators of ref visible from ctx to instance: the
+// ones registered with Ref.Decorate in registration order, then the ones
+// bound in the container, then the local ones from the outermost context
+// inwards. Each decorator wraps the result of the previous one, so the last
+// one is the outermost.
+func decorate[T any](ctx *Context, ref *Ref[T], instance T) T {
+	for _, fn := range ref.decoratorsIn(ctx) {
+		instance = fn(ctx, instance)
+	}
+	return instance
+}
+
+// decoratorsIn returns the decorators of ref visible from ctx, in the order
+// they apply. A ref standing in for a provider has those of the ref it was
+// resolved for.
+func (r *Ref[T]) decoratorsIn(ctx *Context) []func(ctx *Context, inner T) T {
+	source := r.decoratorSource()
+	source.decoratorsMu.RLock()
+	decorators := append([]func(ctx *Context, inner T) T(nil), source.decorators...)
+	source.decoratorsMu.RUnlock()
+
+	for _, current := range ctx.providerChain() {
+		local, _ := current.localProviders[decoratorKey{ref: source}].([]func(ctx *Context, inner T) T)
+		decorators = append(decorators, local...)
+	}
+	return decorators
+}
+
+// hasDecorators reports whether any decorator of r is visible from ctx
+func (r *Ref[T]) hasDecorators(ctx *Context) bool {
+	return len(r.decoratorsIn(ctx)) > 0
+}
+
+func (r *Ref[T]) decoratorSource() *Ref[T] {
+	if r.overridden != nil {
+		return r.overridden
+	}
+	return r
+}
+
+// decorated returns the ref that resolves provider, found for r in the
+// context chain of ctx, with the decorators of r applied
+func (r *Ref[T]) decorated(ctx *Context, provider *Ref[T]) *Ref[T] {
+	if (provider != r || provider.isValue) && r.hasDecorators(ctx) {
+		return r.decoratedProvider(provider)
+	}
+	return provider
+}
+
+// decoratedProvider returns the ref standing in for provider when r, which
+// has decorators, resolves to it. It resolves provider like r would have and
+// applies the decorators of r to the instance, so they are cached together
+// with the same mode and in the same place as provider. As it looks provider
+// up again, r needs one such ref per mode rather than per provider. It
+// describes itself as r but is left out of the dependency graph, which
+// records provider instead.
+//
+// The decorated instance is not disposed of, as the instance of provider is.
+func (r *Ref[T]) decoratedProvider(provider *Ref[T]) *Ref[T] {
+	r.decoratorsMu.Lock()
+	defer r.decoratorsMu.Unlock()
+	if decorated, ok := r.decoratedProviders[provider.mode]; ok {
+		return decorated
+	}
+
+	decorated := &Ref[T]{
+		factory: func(ctx *Context) (T, error) {
+			provider, owner := findRefInContext(ctx, r)
+			return resolveProvider(ctx, provider, owner)
+		},
+		mode:       provider.mode,
+		dispose:    func(instance T) error { return nil },
+		overridden: r,
+	}
+	if r.decoratedProviders == nil {
+		r.decoratedProviders = make(map[Mode]*Ref[T])
+	}
+	r.decoratedProviders[provider.mode] = decorated
+	return decorated
+}
+
+// decoratedOwner returns the innermost context from ctx up to owner that
+// registers decorators for ref, or owner if there is none
+func decoratedOwner[T any](ctx *Context, ref *Ref[T], owner *Context) *Context {
+	key := decoratorKey{ref: ref.decoratorSource()}
+	for current := ctx; current != nil && current != owner; current = current.parent {
+		if _, ok := current.localProviders[key]; ok {
+			return current
+		}
+	}
+	return owner
+}
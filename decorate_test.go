@@ -0,0 +1,305 @@
+package ioc
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+type greeter interface {
+	Greet() string
+}
+
+type plainGreeter struct{}
+
+func (plainGreeter) Greet() string { return "hello" }
+
+type wrappedGreeter struct {
+	inner  greeter
+	prefix string
+}
+
+func (g wrappedGreeter) Greet() string { return g.prefix + "(" + g.inner.Greet() + ")" }
+
+func wrapGreeter(prefix string) func(ctx *Context, inner greeter) greeter {
+	return func(ctx *Context, inner greeter) greeter {
+		return wrappedGreeter{inner: inner, prefix: prefix}
+	}
+}
+
+func TestDecorateWrapsInstanceOnce(t *testing.T) {
+	t.Parallel()
+
+	constructed, decorated := 0, 0
+	greeterRef := Provide(func(ctx *Context) greeter {
+		constructed++
+		return plainGreeter{}
+	})
+	greeterRef.Decorate(func(ctx *Context, inner greeter) greeter {
+		decorated++
+		return wrappedGreeter{inner: inner, prefix: "log"}
+	})
+
+	c := NewContainer()
+	first := RunInContainer(c, func(ctx *Context) greeter { return Inject(ctx, greeterRef) })
+	second := RunInContainer(c, func(ctx *Context) greeter { return Inject(ctx, greeterRef) })
+
+	if first.Greet() != "log(hello)" {
+		t.Errorf("expected 'log(hello)', got %q", first.Greet())
+	}
+	if first != second {
+		t.Error("expected the decorated instance to be cached")
+	}
+	if constructed != 1 || decorated != 1 {
+		t.Errorf("expected one construction and one decoration, got %d and %d", constructed, decorated)
+	}
+}
+
+func TestDecoratorsStackInOrder(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+	greeterRef.Decorate(wrapGreeter("global1"))
+	greeterRef.Decorate(wrapGreeter("global2"))
+
+	innerRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, greeterRef).Greet()
+	}, ProvideOptions[string]{Mode: ModeStandalone, Providers: []any{
+		Decorate(greeterRef, wrapGreeter("inner")),
+	}})
+	outerRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, innerRef)
+	}, ProvideOptions[string]{Mode: ModeStandalone, Providers: []any{
+		Decorate(greeterRef, wrapGreeter("outer1")),
+		Decorate(greeterRef, wrapGreeter("outer2")),
+	}})
+
+	greeting := RunInContainer(NewContainer(), func(ctx *Context) string {
+		return Inject(ctx, outerRef)
+	})
+
+	expected := "inner(outer2(outer1(global2(global1(hello)))))"
+	if greeting != expected {
+		t.Errorf("expected %q, got %q", expected, greeting)
+	}
+}
+
+func TestLocalDecoratorKeepsGlobalInstanceUndecorated(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+	decoratedRef := Provide(func(ctx *Context) greeter {
+		return Inject(ctx, greeterRef)
+	}, ProvideOptions[greeter]{Providers: []any{
+		Decorate(greeterRef, wrapGreeter("local")),
+	}})
+
+	ctx := NewContainer().NewContext()
+	defer ctx.Close()
+	local := Inject(ctx, decoratedRef)
+	global := Inject(ctx, greeterRef)
+
+	if local.Greet() != "local(hello)" {
+		t.Errorf("expected 'local(hello)', got %q", local.Greet())
+	}
+	if global.Greet() != "hello" {
+		t.Errorf("expected the container singleton to stay undecorated, got %q", global.Greet())
+	}
+}
+
+func TestDecoratorAppliesToOverride(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Token[greeter]("greeter")
+	plainRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+
+	ctx := NewContainer().NewContext(WithProviders(
+		Override(greeterRef, plainRef),
+		Decorate(plainRef, wrapGreeter("retry")),
+	))
+	defer ctx.Close()
+
+	if greeting := Inject(ctx, greeterRef).Greet(); greeting != "retry(hello)" {
+		t.Errorf("expected 'retry(hello)', got %q", greeting)
+	}
+}
+
+func TestDecoratorInjectsDependencies(t *testing.T) {
+	t.Parallel()
+
+	prefixRef := Provide(func(ctx *Context) string { return "cached" })
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+	greeterRef.Decorate(func(ctx *Context, inner greeter) greeter {
+		return wrappedGreeter{inner: inner, prefix: Inject(ctx, prefixRef)}
+	})
+
+	c := NewContainer()
+	c.RecordGraph()
+	greeting := RunInContainer(c, func(ctx *Context) string {
+		return Inject(ctx, greeterRef).Greet()
+	})
+
+	if greeting != "cached(hello)" {
+		t.Errorf("expected 'cached(hello)', got %q", greeting)
+	}
+	if deps := c.Graph().Dependencies(greeterRef); len(deps) != 1 {
+		t.Errorf("expected the decorator's dependency to be recorded, got %v", deps)
+	}
+}
+
+func TestDecoratorPanicReportsChain(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} }, ProvideOptions[greeter]{Name: "greeter"})
+	greeterRef.Decorate(func(ctx *Context, inner greeter) greeter {
+		panic("decorator failed")
+	})
+
+	ctx := NewContainer().NewContext()
+	defer ctx.Close()
+	_, err := InjectE(ctx, greeterRef)
+
+	var panicErr *FactoryPanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected a *FactoryPanicError, got %v", err)
+	}
+	if len(panicErr.Chain) != 1 || panicErr.Chain[0].Name != "greeter" {
+		t.Errorf("expected the chain to end at the decorated ref, got %v", panicErr.Chain)
+	}
+}
+
+func TestDecorateRejectsNilDecorator(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+
+	defer func() {
+		var providerErr *ProviderError
+		if err, _ := recover().(error); !errors.As(err, &providerErr) {
+			t.Errorf("expected a *ProviderError, got %v", err)
+		}
+	}()
+	NewContainer().NewContext(WithProviders(Decorate(greeterRef, nil)))
+}
+
+func TestDecoratorOfOverriddenRef(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Token[greeter]("greeter")
+	plainRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+	plainRef.Decorate(wrapGreeter("plain"))
+
+	ctx := NewContainer().NewContext(WithProviders(
+		Override(greeterRef, plainRef),
+		Decorate(greeterRef, wrapGreeter("log")),
+	))
+	defer ctx.Close()
+
+	if greeting := Inject(ctx, greeterRef).Greet(); greeting != "log(plain(hello))" {
+		t.Errorf("expected 'log(plain(hello))', got %q", greeting)
+	}
+	if greeting := Inject(ctx, plainRef).Greet(); greeting != "plain(hello)" {
+		t.Errorf("expected the provider itself to keep only its own decorators, got %q", greeting)
+	}
+}
+
+func TestDecoratorOfBoundToken(t *testing.T) {
+	t.Parallel()
+
+	var constructed, decorated int32
+	greeterRef := Token[greeter]("greeter")
+	greeterRef.Decorate(func(ctx *Context, inner greeter) greeter {
+		atomic.AddInt32(&decorated, 1)
+		return wrappedGreeter{inner: inner, prefix: "log"}
+	})
+	plainRef := Provide(func(ctx *Context) greeter {
+		atomic.AddInt32(&constructed, 1)
+		return plainGreeter{}
+	})
+
+	c := NewContainer()
+	c.RecordGraph()
+	c.Bind(Override(greeterRef, plainRef))
+	first := RunInContainer(c, func(ctx *Context) greeter { return Inject(ctx, greeterRef) })
+	second := RunInContainer(c, func(ctx *Context) greeter { return Inject(ctx, greeterRef) })
+
+	if first.Greet() != "log(hello)" {
+		t.Errorf("expected 'log(hello)', got %q", first.Greet())
+	}
+	if first != second || constructed != 1 || decorated != 1 {
+		t.Errorf("expected one decorated singleton, got %d constructions and %d decorations", constructed, decorated)
+	}
+	if plain := RunInContainer(c, func(ctx *Context) greeter { return Inject(ctx, plainRef) }); plain.Greet() != "hello" {
+		t.Errorf("expected the provider's singleton to stay undecorated, got %q", plain.Greet())
+	}
+	if node, ok := c.Graph().Node(plainRef); !ok || node.Constructed != 1 {
+		t.Errorf("expected the provider of the token in the graph, got %+v", node)
+	}
+}
+
+func TestDecoratorOfValues(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+	greeterRef.Decorate(wrapGreeter("global"))
+	fixedRef := ProvideValue[greeter](plainGreeter{})
+	fixedRef.Decorate(wrapGreeter("fixed"))
+
+	ctx := NewContainer().NewContext(WithProviders(
+		Value[greeter](greeterRef, plainGreeter{}),
+		Decorate(greeterRef, wrapGreeter("local")),
+	))
+	defer ctx.Close()
+
+	if greeting := Inject(ctx, greeterRef).Greet(); greeting != "local(global(hello))" {
+		t.Errorf("expected 'local(global(hello))', got %q", greeting)
+	}
+	if greeting := Inject(ctx, fixedRef).Greet(); greeting != "fixed(hello)" {
+		t.Errorf("expected 'fixed(hello)', got %q", greeting)
+	}
+	if Inject(ctx, fixedRef) != Inject(ctx, fixedRef) {
+		t.Error("expected the decorated value to be cached")
+	}
+}
+
+func TestDecoratorOfOneTargetOfSharedProvider(t *testing.T) {
+	t.Parallel()
+
+	primaryRef := Token[greeter]("primary")
+	fallbackRef := Token[greeter]("fallback")
+	plainRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+
+	ctx := NewContainer().NewContext(WithProviders(
+		Override(primaryRef, plainRef),
+		Override(fallbackRef, plainRef),
+		Decorate(primaryRef, wrapGreeter("retry")),
+	))
+	defer ctx.Close()
+
+	if greeting := Inject(ctx, primaryRef).Greet(); greeting != "retry(hello)" {
+		t.Errorf("expected 'retry(hello)', got %q", greeting)
+	}
+	if greeting := Inject(ctx, fallbackRef).Greet(); greeting != "hello" {
+		t.Errorf("expected the other target to stay undecorated, got %q", greeting)
+	}
+}
+
+func TestDecoratorOfPerContextValuesKeepsOneStandIn(t *testing.T) {
+	t.Parallel()
+
+	greeterRef := Provide(func(ctx *Context) greeter { return plainGreeter{} })
+	greeterRef.Decorate(wrapGreeter("log"))
+
+	c := NewContainer()
+	for _, prefix := range []string{"a", "b", "c"} {
+		ctx := c.NewContext(WithProviders(Value[greeter](greeterRef, wrappedGreeter{inner: plainGreeter{}, prefix: prefix})))
+		if greeting, expected := Inject(ctx, greeterRef).Greet(), "log("+prefix+"(hello))"; greeting != expected {
+			t.Errorf("expected %q, got %q", expected, greeting)
+		}
+		ctx.Close()
+	}
+
+	if len(greeterRef.decoratedProviders) != 1 {
+		t.Errorf("expected the values to share one stand-in, got %d", len(greeterRef.decoratedProviders))
+	}
+}
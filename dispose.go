@@ -0,0 +1,113 @@
+package ioc
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// disposer records what a context has to release when it is closed
+type disposer struct {
+	mu sync.Mutex
+	// releases holds instance disposers and child context closers in
+	// creation order
+	releases []func() error
+	closed   bool
+}
+
+// Close disposes of every instance created by the context in reverse creation
+// order, including those of the child contexts created for local providers.
+// Instances created while constructing a global singleton live as long as
+// the singleton and are not disposed. Errors are joined; closing a context
+// again does nothing.
+func (ctx *Context) Close() error {
+	return ctx.disposer.close()
+}
+
+func (d *disposer) close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	releases := d.releases
+	d.releases = nil
+	d.mu.Unlock()
+
+	var errs []error
+	for i := len(releases) - 1; i >= 0; i-- {
+		errs = append(errs, releases[i]())
+	}
+	return errors.Join(errs...)
+}
+
+// trackDisposal registers instance for disposal when ctx is closed, or with
+// the singleton being constructed
+func (ctx *Context) trackDisposal(ref refMarker, instance any) {
+	if d := ctx.releaser(); d != nil {
+		if release := releaseFunc(ref, instance); release != nil {
+			d.add(release)
+		}
+	}
+}
+
+// releaseFunc returns the function disposing of instance, or nil if it needs
+// none
+func releaseFunc(ref refMarker, instance any) func() error {
+	if release := ref.disposeFunc(instance); release != nil {
+		return release
+	}
+	if closer, ok := instance.(io.Closer); ok {
+		return closer.Close
+	}
+	return nil
+}
+
+// trackChild closes child when ctx is closed, or with the singleton being
+// constructed
+func (ctx *Context) trackChild(child *Context) {
+	if d := ctx.releaser(); d != nil {
+		d.add(child.Close)
+	}
+}
+
+// releaser returns the disposer of what ctx creates: the one of the singletons
+// being constructed, which is nil for those of the container as they are
+// never disposed of, or else the one of ctx
+func (ctx *Context) releaser() *disposer {
+	if ctx.detached {
+		return nil
+	}
+	if store := ctx.resolving.global(); store != nil {
+		return store.disposer
+	}
+	return ctx.disposer
+}
+
+// disposeFunc implements refMarker interface
+func (r *Ref[T]) disposeFunc(instance any) func() error {
+	if r.dispose == nil {
+		return nil
+	}
+	return func() error {
+		return r.dispose(instance.(T))
+	}
+}
+
+func (d *disposer) add(release func() error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.releases = append(d.releases, release)
+}
+
+// global returns the singletons of the global instance the chain is
+// constructing, or nil if it constructs none
+func (r *resolution) global() *singletons {
+	for current := r; current != nil; current = current.parent {
+		if current.call != nil {
+			return current.call.store
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package ioc
+
+import (
+	"errors"
+	"testing"
+)
+
+type closable struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c *closable) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func TestCloseDisposesInstancesInReverseCreationOrder(t *testing.T) {
+	ResetGlobalInstances()
+
+	var closed []string
+
+	txRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "tx", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+
+	repoRef := Provide(func(ctx *Context) *closable {
+		Inject(ctx, txRef)
+		return &closable{name: "repo", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+
+	type Handler struct{ name string }
+	handlerRef := Provide(func(ctx *Context) *Handler {
+		Inject(ctx, repoRef)
+		return &Handler{name: "handler"}
+	}, ProvideOptions[*Handler]{
+		Mode: ModeStandalone,
+		Dispose: func(h *Handler) error {
+			closed = append(closed, h.name)
+			return nil
+		},
+	})
+
+	ctx := NewContext()
+	Inject(ctx, handlerRef)
+
+	if len(closed) != 0 {
+		t.Fatalf("expected nothing closed before Close, got %v", closed)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []string{"handler", "repo", "tx"}
+	if len(closed) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, closed)
+	}
+	for i := range expected {
+		if closed[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, closed)
+			break
+		}
+	}
+
+	// Closing twice does nothing
+	if err := ctx.Close(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(closed) != len(expected) {
+		t.Errorf("expected no further disposal, got %v", closed)
+	}
+}
+
+func TestCloseDisposesChildContextsOfLocalProviders(t *testing.T) {
+	ResetGlobalInstances()
+
+	var closed []string
+
+	connRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "conn", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+
+	testConnRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "test conn", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone, Overrides: connRef})
+
+	serviceRef := Provide(func(ctx *Context) *closable {
+		Inject(ctx, connRef)
+		return &closable{name: "service", closed: &closed}
+	}, ProvideOptions[*closable]{
+		Mode:      ModeStandalone,
+		Providers: []any{testConnRef},
+	})
+
+	ctx := NewContext()
+	Inject(ctx, serviceRef)
+
+	if err := ctx.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(closed) != 2 || closed[0] != "service" || closed[1] != "test conn" {
+		t.Errorf("expected [service test conn], got %v", closed)
+	}
+}
+
+func TestCloseSkipsGlobalSingletonsAndTheirDependencies(t *testing.T) {
+	ResetGlobalInstances()
+
+	var closed []string
+
+	connRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "conn", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+
+	poolRef := Provide(func(ctx *Context) *closable {
+		Inject(ctx, connRef)
+		return &closable{name: "pool", closed: &closed}
+	})
+
+	ctx := NewContext()
+	Inject(ctx, poolRef)
+
+	if err := ctx.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(closed) != 0 {
+		t.Errorf("expected singleton and its dependencies to stay open, got %v", closed)
+	}
+}
+
+func TestCloseJoinsDisposalErrors(t *testing.T) {
+	ResetGlobalInstances()
+
+	var closed []string
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+
+	firstRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "first", closed: &closed, err: errFirst}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+	secondRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "second", closed: &closed, err: errSecond}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+
+	ctx := NewContext()
+	Inject(ctx, firstRef)
+	Inject(ctx, secondRef)
+
+	err := ctx.Close()
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("expected both errors, got %v", err)
+	}
+	if len(closed) != 2 {
+		t.Errorf("expected both instances to be closed, got %v", closed)
+	}
+}
@@ -0,0 +1,186 @@
+package ioc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RefInfo describes a ref for diagnostics
+type RefInfo struct {
+	// Ref is the *Ref[T] being described
+	Ref  any
+	Name string
+	Type string
+	// Location is the file:line where the ref was provided
+	Location string
+}
+
+// String formats the ref as Ref[Type] "name" (file:line), leaving out the
+// parts that are unknown
+func (i RefInfo) String() string {
+	var b strings.Builder
+	b.WriteString("Ref[" + i.Type + "]")
+	if i.Name != "" {
+		b.WriteString(" " + strconv.Quote(i.Name))
+	}
+	if i.Location != "" {
+		b.WriteString(" (" + i.Location + ")")
+	} else if i.Name == "" {
+		fmt.Fprintf(&b, "(%p)", i.Ref)
+	}
+	return b.String()
+}
+
+// ErrNotProvided is the error of a *ResolutionError raised for a token that
+// is not overridden in the context chain
+var ErrNotProvided = errors.New("token is not provided")
+
+// ResolutionError reports a factory failure and the chain of refs that led to it
+type ResolutionError struct {
+	// Chain lists the refs being resolved, outermost first, ending with the
+	// ref whose factory failed
+	Chain []RefInfo
+	Err   error
+}
+
+// Error implements the error interface
+func (e *ResolutionError) Error() string {
+	return fmt.Sprintf("Failed to resolve %s: %v", formatChain(e.Chain), e.Err)
+}
+
+// Unwrap returns the error returned by the failing factory
+func (e *ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// CircularDependencyError reports a ref that depends on itself
+type CircularDependencyError struct {
+	// Path lists the refs being resolved, outermost first, ending with the
+	// ref that was requested again
+	Path []RefInfo
+}
+
+// Error implements the error interface
+func (e *CircularDependencyError) Error() string {
+	return "Circular dependency detected: " + formatChain(e.Path)
+}
+
+// FactoryPanicError wraps a value recovered from a panicking factory
+type FactoryPanicError struct {
+	// Chain lists the refs being resolved, outermost first, ending with the
+	// ref whose factory panicked
+	Chain []RefInfo
+	Value any
+	// Stack is the stack trace of the goroutine at the time of the panic
+	Stack []byte
+}
+
+// Error implements the error interface
+func (e *FactoryPanicError) Error() string {
+	return fmt.Sprintf("Factory panicked while resolving %s: %v", formatChain(e.Chain), e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *FactoryPanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// LifecycleError reports a failing OnStart or OnStop hook
+type LifecycleError struct {
+	Ref RefInfo
+	// Hook is either "OnStart" or "OnStop"
+	Hook string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *LifecycleError) Error() string {
+	return fmt.Sprintf("%s hook of %s failed: %v", e.Hook, e.Ref, e.Err)
+}
+
+// Unwrap returns the error returned by the hook
+func (e *LifecycleError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderError reports an entry of ProvideOptions.Providers that cannot be
+// registered, such as a ref overriding a ref of another type
+type ProviderError struct {
+	Provider any
+	Reason   string
+	// Err is the error behind the rejection, if any. It is the reason when
+	// Reason is empty.
+	Err error
+}
+
+// Error implements the error interface
+func (e *ProviderError) Error() string {
+	reason := e.Reason
+	if reason == "" && e.Err != nil {
+		reason = e.Err.Error()
+	}
+	if ref, ok := e.Provider.(refMarker); ok {
+		return fmt.Sprintf("Invalid provider %s: %s", ref.describe(), reason)
+	}
+	return fmt.Sprintf("Invalid provider %T: %s", e.Provider, reason)
+}
+
+// Unwrap returns the error behind the rejection
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateKeyError reports a key contributed twice to a MapBinding
+type DuplicateKeyError struct {
+	// Binding describes the map binding
+	Binding string
+	Key     any
+	// Refs are the ref contributed first and the one contributed again
+	Refs []RefInfo
+}
+
+// Error implements the error interface
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("Duplicate key %v in %s: contributed by %s", e.Key, e.Binding, formatRefs(e.Refs))
+}
+
+// asInjectionError reports whether a recovered value or factory error was
+// raised by the library itself
+func asInjectionError(value any) (error, bool) {
+	err, ok := value.(error)
+	if !ok {
+		return nil, false
+	}
+	var resErr *ResolutionError
+	var cycleErr *CircularDependencyError
+	var panicErr *FactoryPanicError
+	if errors.As(err, &resErr) || errors.As(err, &cycleErr) || errors.As(err, &panicErr) {
+		return err, true
+	}
+	return nil, false
+}
+
+// formatRefs lists refs as "A and B" or "A, B and C"
+func formatRefs(refs []RefInfo) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = ref.String()
+	}
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+func formatChain(chain []RefInfo) string {
+	parts := make([]string, len(chain))
+	for i, ref := range chain {
+		parts[i] = ref.String()
+	}
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,76 @@
+package ioc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRefStringIncludesNameTypeAndLocation(t *testing.T) {
+	type Config struct{}
+
+	named := Provide(func(ctx *Context) *Config {
+		return &Config{}
+	}, ProvideOptions[*Config]{Name: "config"})
+	unnamed := Provide(func(ctx *Context) string { return "" })
+
+	if named.Name() != "config" {
+		t.Errorf("expected name 'config', got '%s'", named.Name())
+	}
+
+	s := named.String()
+	if !strings.HasPrefix(s, `Ref[*ioc.Config] "config" (errors_test.go:`) {
+		t.Errorf("unexpected description '%s'", s)
+	}
+
+	s = unnamed.String()
+	if !strings.HasPrefix(s, "Ref[string] (errors_test.go:") {
+		t.Errorf("unexpected description '%s'", s)
+	}
+}
+
+func TestRefInfoStringWithoutLocation(t *testing.T) {
+	info := RefInfo{Ref: &Ref[int]{}, Type: "int"}
+	if !strings.HasPrefix(info.String(), "Ref[int](0x") {
+		t.Errorf("expected address fallback, got '%s'", info.String())
+	}
+
+	info.Name = "answer"
+	if info.String() != `Ref[int] "answer"` {
+		t.Errorf("unexpected description '%s'", info.String())
+	}
+}
+
+func TestErrorMessagesUseRefNames(t *testing.T) {
+	ResetGlobalInstances()
+
+	var aRef, bRef *Ref[string]
+	aRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, bRef)
+	}, ProvideOptions[string]{Name: "a"})
+	bRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, aRef)
+	}, ProvideOptions[string]{Name: "b"})
+
+	failingRef := ProvideE(func(ctx *Context) (string, error) {
+		return "", errors.New("boom")
+	}, ProvideOptions[string]{Name: "failing"})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		_, err := InjectE(ctx, aRef)
+		msg := err.Error()
+		if !strings.HasPrefix(msg, "Circular dependency detected: ") {
+			t.Errorf("unexpected message '%s'", msg)
+		}
+		if strings.Count(msg, `"a"`) != 2 || strings.Count(msg, `"b"`) != 1 {
+			t.Errorf("expected path a -> b -> a, got '%s'", msg)
+		}
+
+		_, err = InjectE(ctx, failingRef)
+		msg = err.Error()
+		if !strings.Contains(msg, `"failing"`) || !strings.HasSuffix(msg, ": boom") {
+			t.Errorf("unexpected message '%s'", msg)
+		}
+		return nil
+	})
+}
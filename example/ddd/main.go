@@ -9,7 +9,9 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"os"
 	"time"
 
 	ioc "github.com/MunMunMiao/go-ioc"
@@ -83,6 +85,10 @@ type OrderRepository interface {
 	FindByCustomer(customerID string) ([]*Order, error)
 }
 
+// OrderRepositoryRef is a port: the domain declares it, and the composition
+// root in main decides which adapter provides it
+var OrderRepositoryRef = ioc.Token[OrderRepository]("OrderRepository")
+
 // ============================================================================
 // Domain Layer - Domain Service
 // ============================================================================
@@ -97,7 +103,7 @@ func (s *PricingService) ApplyDiscount(order *Order, discountPercent float64) {
 
 var PricingServiceRef = ioc.Provide(func(ctx *ioc.Context) *PricingService {
 	return &PricingService{}
-})
+}, ioc.ProvideOptions[*PricingService]{Name: "PricingService"})
 
 // ============================================================================
 // Infrastructure Layer - Repository Implementation (Adapter)
@@ -129,11 +135,11 @@ func (r *InMemoryOrderRepository) FindByCustomer(customerID string) ([]*Order, e
 	return result, nil
 }
 
-var OrderRepositoryRef = ioc.Provide(func(ctx *ioc.Context) OrderRepository {
+var InMemoryOrderRepositoryRef = ioc.Provide(func(ctx *ioc.Context) OrderRepository {
 	return &InMemoryOrderRepository{
 		orders: make(map[string]*Order),
 	}
-})
+}, ioc.ProvideOptions[OrderRepository]{Name: "InMemoryOrderRepository"})
 
 // ============================================================================
 // Application Layer - Use Cases
@@ -175,7 +181,7 @@ var CreateOrderUseCaseRef = ioc.Provide(func(ctx *ioc.Context) *CreateOrderUseCa
 		orderRepo:      ioc.Inject(ctx, OrderRepositoryRef),
 		pricingService: ioc.Inject(ctx, PricingServiceRef),
 	}
-})
+}, ioc.ProvideOptions[*CreateOrderUseCase]{Name: "CreateOrderUseCase"})
 
 type ConfirmOrderUseCase struct {
 	orderRepo OrderRepository
@@ -198,7 +204,7 @@ var ConfirmOrderUseCaseRef = ioc.Provide(func(ctx *ioc.Context) *ConfirmOrderUse
 	return &ConfirmOrderUseCase{
 		orderRepo: ioc.Inject(ctx, OrderRepositoryRef),
 	}
-})
+}, ioc.ProvideOptions[*ConfirmOrderUseCase]{Name: "ConfirmOrderUseCase"})
 
 type GetCustomerOrdersUseCase struct {
 	orderRepo OrderRepository
@@ -212,7 +218,7 @@ var GetCustomerOrdersUseCaseRef = ioc.Provide(func(ctx *ioc.Context) *GetCustome
 	return &GetCustomerOrdersUseCase{
 		orderRepo: ioc.Inject(ctx, OrderRepositoryRef),
 	}
-})
+}, ioc.ProvideOptions[*GetCustomerOrdersUseCase]{Name: "GetCustomerOrdersUseCase"})
 
 // ============================================================================
 // Interface Layer - API Handler
@@ -262,13 +268,30 @@ var OrderHandlerRef = ioc.Provide(func(ctx *ioc.Context) *OrderHandler {
 		confirmOrder:      ioc.Inject(ctx, ConfirmOrderUseCaseRef),
 		getCustomerOrders: ioc.Inject(ctx, GetCustomerOrdersUseCaseRef),
 	}
-})
+}, ioc.ProvideOptions[*OrderHandler]{Name: "OrderHandler"})
 
 // ============================================================================
 // Application Entry Point
 // ============================================================================
 
+// bindAdapters binds the ports of the domain to their infrastructure adapters
+func bindAdapters() {
+	ioc.Bind(
+		ioc.Override(OrderRepositoryRef, InMemoryOrderRepositoryRef),
+	)
+}
+
 func main() {
+	bindAdapters()
+
+	graphFormat := flag.String("graph", "", "print the resolved dependency graph as \"dot\" or \"mermaid\" and exit")
+	flag.Parse()
+
+	if *graphFormat != "" {
+		printGraph(*graphFormat)
+		return
+	}
+
 	ioc.RunInInjectionContext(func(ctx *ioc.Context) any {
 		handler := ioc.Inject(ctx, OrderHandlerRef)
 
@@ -300,3 +323,26 @@ func main() {
 		return nil
 	})
 }
+
+// printGraph resolves the handler and prints the wiring that was recorded
+func printGraph(format string) {
+	ioc.RecordGraph()
+	ioc.RunInInjectionContext(func(ctx *ioc.Context) any {
+		return ioc.Inject(ctx, OrderHandlerRef)
+	})
+
+	graph := ioc.ResolvedGraph()
+	var err error
+	switch format {
+	case "dot":
+		err = graph.WriteDOT(os.Stdout)
+	case "mermaid":
+		err = graph.WriteMermaid(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown graph format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,255 @@
+package ioc
+
+import "sync"
+
+// Graph is a snapshot of the dependencies recorded while resolving refs in a
+// container
+type Graph struct {
+	// Nodes lists every resolved provider in the order it was first injected
+	Nodes []GraphNode
+	// Edges lists every dependency in the order it was first injected
+	Edges []GraphEdge
+}
+
+// GraphNode is a provider that has been resolved
+type GraphNode struct {
+	Ref  RefInfo
+	Mode Mode
+	// Constructed counts the instances the factory of the provider created
+	Constructed int
+}
+
+// GraphEdge records that the factory of From injected To
+type GraphEdge struct {
+	From RefInfo
+	// To is the provider that was resolved
+	To RefInfo
+	// Requested is the ref passed to Inject. It differs from To when a local
+	// provider overrode it.
+	Requested RefInfo
+}
+
+// Overridden reports whether the injected ref was replaced by a local provider
+func (e GraphEdge) Overridden() bool {
+	return e.Requested.Ref != e.To.Ref
+}
+
+// Roots returns the nodes no other resolved provider depends on
+func (g *Graph) Roots() []GraphNode {
+	injected := make(map[any]bool)
+	for _, edge := range g.Edges {
+		injected[edge.To.Ref] = true
+	}
+	var roots []GraphNode
+	for _, node := range g.Nodes {
+		if !injected[node.Ref.Ref] {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// Dependencies returns the nodes the factory of ref injected
+func (g *Graph) Dependencies(ref any) []GraphNode {
+	var deps []GraphNode
+	for _, edge := range g.Edges {
+		if edge.From.Ref == ref {
+			deps = append(deps, g.node(edge.To.Ref))
+		}
+	}
+	return deps
+}
+
+// TopologicalOrder returns the nodes with every node after the nodes it
+// depends on, which is the order to start them in. Ties keep the order in
+// which the nodes were first injected. If the recorded graph contains a cycle,
+// a *CircularDependencyError describing it is returned.
+func (g *Graph) TopologicalOrder() ([]GraphNode, error) {
+	pending := make(map[any]int, len(g.Nodes))
+	dependents := make(map[any][]any)
+	for _, edge := range g.Edges {
+		pending[edge.From.Ref]++
+		dependents[edge.To.Ref] = append(dependents[edge.To.Ref], edge.From.Ref)
+	}
+
+	order := make([]GraphNode, 0, len(g.Nodes))
+	done := make(map[any]bool, len(g.Nodes))
+	for len(order) < len(g.Nodes) {
+		progressed := false
+		for _, node := range g.Nodes {
+			ref := node.Ref.Ref
+			if done[ref] || pending[ref] > 0 {
+				continue
+			}
+			done[ref] = true
+			order = append(order, node)
+			for _, dependent := range dependents[ref] {
+				pending[dependent]--
+			}
+			progressed = true
+		}
+		if !progressed {
+			return order, &CircularDependencyError{Path: g.findCycle(done)}
+		}
+	}
+	return order, nil
+}
+
+func (g *Graph) node(ref any) GraphNode {
+	node, _ := g.Node(ref)
+	return node
+}
+
+// Node returns the node of ref, if it has been resolved
+func (g *Graph) Node(ref any) (GraphNode, bool) {
+	for _, node := range g.Nodes {
+		if node.Ref.Ref == ref {
+			return node, true
+		}
+	}
+	return GraphNode{}, false
+}
+
+// findCycle walks dependencies among the nodes not yet ordered until one
+// repeats
+func (g *Graph) findCycle(done map[any]bool) []RefInfo {
+	var start GraphNode
+	for _, node := range g.Nodes {
+		if !done[node.Ref.Ref] {
+			start = node
+			break
+		}
+	}
+
+	var path []RefInfo
+	seen := make(map[any]int)
+	current := start
+	for {
+		if i, ok := seen[current.Ref.Ref]; ok {
+			return append(path[i:], current.Ref)
+		}
+		seen[current.Ref.Ref] = len(path)
+		path = append(path, current.Ref)
+		for _, dep := range g.Dependencies(current.Ref.Ref) {
+			if !done[dep.Ref.Ref] {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// graphRecorder collects the nodes and edges of a container as refs are
+// injected
+type graphRecorder struct {
+	mu    sync.RWMutex
+	nodes []refMarker
+	seen  map[refMarker]bool
+	edges []graphEdge
+	known map[graphEdge]bool
+	// constructed counts the instances created by each provider
+	constructed map[refMarker]int
+}
+
+type graphEdge struct {
+	from      refMarker
+	to        refMarker
+	requested refMarker
+}
+
+func newGraphRecorder() *graphRecorder {
+	return &graphRecorder{
+		seen:        make(map[refMarker]bool),
+		known:       make(map[graphEdge]bool),
+		constructed: make(map[refMarker]int),
+	}
+}
+
+// record notes that the innermost ref of chain injected requested, which
+// resolved to actual. A nil recorder records nothing.
+func (g *graphRecorder) record(chain *resolution, requested, actual refMarker) {
+	if g == nil {
+		return
+	}
+	var edge graphEdge
+	if chain != nil {
+		edge = graphEdge{from: chain.ref, to: actual, requested: requested}
+	}
+
+	g.mu.RLock()
+	recorded := g.seen[actual] && (chain == nil || g.known[edge])
+	g.mu.RUnlock()
+	if recorded {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.seen[actual] {
+		g.seen[actual] = true
+		g.nodes = append(g.nodes, actual)
+	}
+	if chain != nil && !g.known[edge] {
+		g.known[edge] = true
+		g.edges = append(g.edges, edge)
+	}
+}
+
+// construct notes that the factory of ref created an instance
+func (g *graphRecorder) construct(ref refMarker) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.constructed[ref]++
+}
+
+func (g *graphRecorder) snapshot() *Graph {
+	if g == nil {
+		return &Graph{}
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	graph := &Graph{
+		Nodes: make([]GraphNode, len(g.nodes)),
+		Edges: make([]GraphEdge, len(g.edges)),
+	}
+	for i, ref := range g.nodes {
+		graph.Nodes[i] = GraphNode{Ref: ref.describe(), Mode: ref.getMode(), Constructed: g.constructed[ref]}
+	}
+	for i, edge := range g.edges {
+		graph.Edges[i] = GraphEdge{
+			From:      edge.from.describe(),
+			To:        edge.to.describe(),
+			Requested: edge.requested.describe(),
+		}
+	}
+	return graph
+}
+
+// RecordGraph starts recording the dependencies of the default container
+func RecordGraph() {
+	defaultContainer.RecordGraph()
+}
+
+// ResolvedGraph returns the dependencies recorded in the default container
+func ResolvedGraph() *Graph {
+	return defaultContainer.Graph()
+}
+
+// RecordGraph starts recording the dependencies resolved in the container.
+// Recording is off by default, as it costs every Inject a lookup and keeps
+// every provider resolved, the ones of per-request overrides included, for
+// the lifetime of the container. Calling it again does nothing.
+func (c *Container) RecordGraph() {
+	c.graph.CompareAndSwap(nil, newGraphRecorder())
+}
+
+// Graph returns the dependencies recorded while resolving refs in the
+// container since RecordGraph was called or the container was last reset. It
+// is empty if the container does not record its graph.
+func (c *Container) Graph() *Graph {
+	return c.recorder().snapshot()
+}
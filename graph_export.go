@@ -0,0 +1,114 @@
+package ioc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// overrideColor marks edges that were redirected to a local provider
+const overrideColor = "#d62728"
+
+// WriteDOT writes the graph in Graphviz DOT format. Nodes are labeled with
+// the name, type and mode of their ref, and edges redirected to a local
+// provider are drawn in red and labeled with the ref they override.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	ids := g.nodeIDs()
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "digraph ioc {")
+	fmt.Fprintln(out, "  node [shape=box];")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(out, "  %s [label=\"%s\"];\n", ids[node.Ref.Ref], escapeDOT(nodeLabel(node)))
+	}
+	for _, edge := range g.Edges {
+		from, to := ids[edge.From.Ref], ids[edge.To.Ref]
+		if edge.Overridden() {
+			fmt.Fprintf(out, "  %s -> %s [color=\"%s\", fontcolor=\"%s\", label=\"%s\"];\n",
+				from, to, overrideColor, overrideColor, escapeDOT(overrideLabel(edge)))
+		} else {
+			fmt.Fprintf(out, "  %s -> %s;\n", from, to)
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, using the same labels
+// and colors as WriteDOT
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	ids := g.nodeIDs()
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "graph TD")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(out, "  %s[\"%s\"]\n", ids[node.Ref.Ref], escapeMermaid(nodeLabel(node)))
+	}
+	var overridden []int
+	for i, edge := range g.Edges {
+		from, to := ids[edge.From.Ref], ids[edge.To.Ref]
+		if edge.Overridden() {
+			fmt.Fprintf(out, "  %s -->|\"%s\"| %s\n", from, escapeMermaid(overrideLabel(edge)), to)
+			overridden = append(overridden, i)
+		} else {
+			fmt.Fprintf(out, "  %s --> %s\n", from, to)
+		}
+	}
+	for _, i := range overridden {
+		fmt.Fprintf(out, "  linkStyle %d stroke:%s,color:%s\n", i, overrideColor, overrideColor)
+	}
+	return out.Flush()
+}
+
+func (g *Graph) nodeIDs() map[any]string {
+	ids := make(map[any]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.Ref.Ref] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+// nodeLabel returns the lines describing a node: its name, or where it was
+// provided if it has none, followed by its type and mode
+func nodeLabel(node GraphNode) []string {
+	first := node.Ref.Name
+	if first == "" {
+		first = node.Ref.Location
+	}
+	lines := []string{node.Ref.Type, node.Mode.String()}
+	if first != "" {
+		lines = append([]string{first}, lines...)
+	}
+	return lines
+}
+
+func overrideLabel(edge GraphEdge) []string {
+	requested := edge.Requested.Name
+	if requested == "" {
+		requested = edge.Requested.Location
+	}
+	if requested == "" {
+		requested = edge.Requested.Type
+	}
+	return []string{"overrides " + requested}
+}
+
+func escapeDOT(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, `\`, `\\`)
+		escaped[i] = strings.ReplaceAll(line, `"`, `\"`)
+	}
+	return strings.Join(escaped, `\n`)
+}
+
+func escapeMermaid(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, `"`, "#quot;")
+		line = strings.ReplaceAll(line, "<", "#lt;")
+		escaped[i] = strings.ReplaceAll(line, ">", "#gt;")
+	}
+	return strings.Join(escaped, "<br/>")
+}
@@ -0,0 +1,90 @@
+package ioc
+
+import (
+	"strings"
+	"testing"
+)
+
+func exportGraph(t *testing.T) *Graph {
+	t.Helper()
+
+	configRef := Provide(func(ctx *Context) string {
+		return "config"
+	}, ProvideOptions[string]{Name: "config"})
+	testConfigRef := Provide(func(ctx *Context) string {
+		return "test config"
+	}, ProvideOptions[string]{Name: "test \"config\"", Overrides: configRef})
+	repoRef := Provide(func(ctx *Context) map[string]int {
+		Inject(ctx, configRef)
+		return nil
+	}, ProvideOptions[map[string]int]{Name: "repo", Mode: ModeStandalone})
+	serviceRef := Provide(func(ctx *Context) string {
+		Inject(ctx, repoRef)
+		return "service"
+	}, ProvideOptions[string]{Name: "service", Providers: []any{testConfigRef}})
+
+	container := NewContainer()
+	container.RecordGraph()
+	Inject(container.NewContext(), serviceRef)
+	return container.Graph()
+}
+
+func TestWriteDOT(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	if err := exportGraph(t).WriteDOT(&b); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `digraph ioc {
+  node [shape=box];
+  n0 [label="service\nstring\nglobal"];
+  n1 [label="repo\nmap[string]int\nstandalone"];
+  n2 [label="test \"config\"\nstring\nglobal"];
+  n0 -> n1;
+  n1 -> n2 [color="#d62728", fontcolor="#d62728", label="overrides config"];
+}
+`
+	if b.String() != expected {
+		t.Errorf("unexpected DOT output:\n%s", b.String())
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	if err := exportGraph(t).WriteMermaid(&b); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `graph TD
+  n0["service<br/>string<br/>global"]
+  n1["repo<br/>map[string]int<br/>standalone"]
+  n2["test #quot;config#quot;<br/>string<br/>global"]
+  n0 --> n1
+  n1 -->|"overrides config"| n2
+  linkStyle 1 stroke:#d62728,color:#d62728
+`
+	if b.String() != expected {
+		t.Errorf("unexpected Mermaid output:\n%s", b.String())
+	}
+}
+
+func TestUnnamedNodesAreLabeledWithLocation(t *testing.T) {
+	t.Parallel()
+
+	ref := Provide(func(ctx *Context) int { return 1 })
+	container := NewContainer()
+	container.RecordGraph()
+	Inject(container.NewContext(), ref)
+
+	var b strings.Builder
+	if err := container.Graph().WriteDOT(&b); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(b.String(), `[label="graph_export_test.go:`) {
+		t.Errorf("expected location in label, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,237 @@
+package ioc
+
+import (
+	"errors"
+	"testing"
+)
+
+func refsOf(nodes []GraphNode) []any {
+	refs := make([]any, len(nodes))
+	for i, node := range nodes {
+		refs[i] = node.Ref.Ref
+	}
+	return refs
+}
+
+func TestGraphRecordsEdgesAndOverrides(t *testing.T) {
+	t.Parallel()
+
+	configRef := Provide(func(ctx *Context) string {
+		return "config"
+	}, ProvideOptions[string]{Name: "config"})
+	testConfigRef := Provide(func(ctx *Context) string {
+		return "test config"
+	}, ProvideOptions[string]{Name: "test config", Overrides: configRef})
+
+	repoRef := Provide(func(ctx *Context) string {
+		return "repo:" + Inject(ctx, configRef)
+	}, ProvideOptions[string]{Name: "repo", Mode: ModeStandalone})
+
+	serviceRef := Provide(func(ctx *Context) string {
+		return "service:" + Inject(ctx, repoRef)
+	}, ProvideOptions[string]{Name: "service", Providers: []any{testConfigRef}})
+
+	container := NewContainer()
+	container.RecordGraph()
+	RunInContainer(container, func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	graph := container.Graph()
+
+	nodes := refsOf(graph.Nodes)
+	expectedNodes := []any{serviceRef, repoRef, testConfigRef}
+	if len(nodes) != len(expectedNodes) {
+		t.Fatalf("expected nodes %v, got %v", expectedNodes, graph.Nodes)
+	}
+	for i := range expectedNodes {
+		if nodes[i] != expectedNodes[i] {
+			t.Errorf("expected nodes %v, got %v", expectedNodes, graph.Nodes)
+			break
+		}
+	}
+	if graph.Nodes[1].Mode != ModeStandalone {
+		t.Errorf("expected repo to be standalone, got %v", graph.Nodes[1].Mode)
+	}
+
+	if len(graph.Edges) != 2 {
+		t.Fatalf("expected 2 edges, got %v", graph.Edges)
+	}
+	if edge := graph.Edges[0]; edge.From.Ref != serviceRef || edge.To.Ref != repoRef || edge.Overridden() {
+		t.Errorf("unexpected edge %v", edge)
+	}
+	edge := graph.Edges[1]
+	if edge.From.Ref != repoRef || edge.To.Ref != testConfigRef || edge.Requested.Ref != configRef || !edge.Overridden() {
+		t.Errorf("expected overridden edge repo -> test config, got %v", edge)
+	}
+
+	roots := graph.Roots()
+	if len(roots) != 1 || roots[0].Ref.Ref != serviceRef {
+		t.Errorf("expected service to be the only root, got %v", roots)
+	}
+}
+
+func TestGraphTopologicalOrder(t *testing.T) {
+	t.Parallel()
+
+	baseRef := Provide(func(ctx *Context) string { return "base" })
+	leftRef := Provide(func(ctx *Context) string { return Inject(ctx, baseRef) })
+	rightRef := Provide(func(ctx *Context) string { return Inject(ctx, baseRef) })
+	topRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, leftRef) + Inject(ctx, rightRef)
+	})
+
+	container := NewContainer()
+	container.RecordGraph()
+	RunInContainer(container, func(ctx *Context) string {
+		return Inject(ctx, topRef)
+	})
+
+	order, err := container.Graph().TopologicalOrder()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	position := make(map[any]int)
+	for i, ref := range refsOf(order) {
+		position[ref] = i
+	}
+	if len(position) != 4 {
+		t.Fatalf("expected 4 nodes, got %v", order)
+	}
+	if position[baseRef] > position[leftRef] || position[baseRef] > position[rightRef] ||
+		position[leftRef] > position[topRef] || position[rightRef] > position[topRef] {
+		t.Errorf("expected dependencies before dependents, got %v", order)
+	}
+}
+
+func TestGraphTopologicalOrderReportsCycles(t *testing.T) {
+	t.Parallel()
+
+	var aRef, bRef *Ref[string]
+	aRef = Provide(func(ctx *Context) string { return Inject(ctx, bRef) })
+	bRef = Provide(func(ctx *Context) string { return Inject(ctx, aRef) })
+
+	container := NewContainer()
+	container.RecordGraph()
+	RunInContainer(container, func(ctx *Context) any {
+		_, _ = InjectE(ctx, aRef)
+		return nil
+	})
+
+	_, err := container.Graph().TopologicalOrder()
+	var cycleErr *CircularDependencyError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("expected *CircularDependencyError, got %v", err)
+	}
+	if len(cycleErr.Path) != 3 || cycleErr.Path[0].Ref != cycleErr.Path[2].Ref {
+		t.Errorf("expected a closed path, got %v", cycleErr.Path)
+	}
+}
+
+func TestContainerResetClearsGraph(t *testing.T) {
+	t.Parallel()
+
+	ref := Provide(func(ctx *Context) string { return "value" })
+
+	container := NewContainer()
+	container.RecordGraph()
+	Inject(container.NewContext(), ref)
+	if len(container.Graph().Nodes) != 1 {
+		t.Fatal("expected one recorded node")
+	}
+
+	container.Reset()
+	if len(container.Graph().Nodes) != 0 {
+		t.Error("expected reset to clear the graph")
+	}
+	Inject(container.NewContext(), ref)
+	if len(container.Graph().Nodes) != 1 {
+		t.Error("expected reset to keep recording")
+	}
+}
+
+func TestGraphIsOnlyRecordedOnRequest(t *testing.T) {
+	t.Parallel()
+
+	ref := Provide(func(ctx *Context) string { return "value" })
+
+	container := NewContainer()
+	Inject(container.NewContext(), ref)
+	if graph := container.Graph(); len(graph.Nodes) != 0 || len(graph.Edges) != 0 {
+		t.Errorf("expected nothing to be recorded, got %+v", graph)
+	}
+
+	container.RecordGraph()
+	Inject(container.NewContext(WithProviders(Value(ref, "fake"))), ref)
+	if len(container.Graph().Nodes) != 1 {
+		t.Errorf("expected the graph to be recorded once requested, got %+v", container.Graph())
+	}
+}
+
+func TestGraphCountsConstructions(t *testing.T) {
+	t.Parallel()
+
+	globalRef := Provide(func(ctx *Context) int { return 1 })
+	transientRef := Provide(func(ctx *Context) int {
+		return Inject(ctx, globalRef)
+	}, ProvideOptions[int]{Mode: ModeTransient})
+	failingRef := ProvideE(func(ctx *Context) (int, error) {
+		return 0, errors.New("unavailable")
+	})
+
+	container := NewContainer()
+	container.RecordGraph()
+	ctx := container.NewContext()
+	Inject(ctx, transientRef)
+	Inject(ctx, transientRef)
+	InjectE(ctx, failingRef)
+	Inject(ctx.Container().NewContext(), globalRef)
+
+	graph := container.Graph()
+	for ref, expected := range map[any]int{globalRef: 1, transientRef: 2, failingRef: 0} {
+		node, ok := graph.Node(ref)
+		if !ok {
+			t.Fatalf("expected a node for %v", ref)
+		}
+		if node.Constructed != expected {
+			t.Errorf("expected %v to be constructed %d times, got %d", node.Ref, expected, node.Constructed)
+		}
+	}
+	if _, ok := graph.Node(Provide(func(ctx *Context) int { return 0 })); ok {
+		t.Error("expected no node for a ref that was never resolved")
+	}
+}
+
+func TestGraphOfDecoratedValue(t *testing.T) {
+	t.Parallel()
+
+	nameRef := ProvideValue("gopher")
+	nameRef.Decorate(func(ctx *Context, inner string) string { return "@" + inner })
+	greetingRef := Provide(func(ctx *Context) string {
+		return "hello " + Inject(ctx, nameRef)
+	})
+
+	container := NewContainer()
+	container.RecordGraph()
+	if greeting := RunInContainer(container, func(ctx *Context) string { return Inject(ctx, greetingRef) }); greeting != "hello @gopher" {
+		t.Fatalf("expected 'hello @gopher', got '%s'", greeting)
+	}
+
+	graph := container.Graph()
+	if len(graph.Nodes) != 2 {
+		t.Errorf("expected one node per ref, got %v", refsOf(graph.Nodes))
+	}
+	for _, edge := range graph.Edges {
+		if edge.From.Ref == edge.To.Ref {
+			t.Errorf("expected no self-edge, got %v", edge)
+		}
+	}
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		t.Fatalf("expected no cycle, got %v", err)
+	}
+	if refs := refsOf(order); len(refs) != 2 || refs[0] != nameRef || refs[1] != greetingRef {
+		t.Errorf("expected the value before its dependent, got %v", refs)
+	}
+}
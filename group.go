@@ -0,0 +1,132 @@
+package ioc
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// Group collects refs providing T, so they can be injected together with
+// InjectAll. Refs from any package can be added to a group.
+type Group[T any] struct {
+	name    string
+	mu      sync.RWMutex
+	members []*Ref[T]
+}
+
+// NewGroup creates an empty group
+func NewGroup[T any](name string) *Group[T] {
+	return &Group[T]{name: name}
+}
+
+// Name returns the name of the group
+func (g *Group[T]) Name() string {
+	return g.name
+}
+
+// String describes the group by type and name
+func (g *Group[T]) String() string {
+	return "Group[" + reflect.TypeOf((*T)(nil)).Elem().String() + "] " + strconv.Quote(g.name)
+}
+
+// Add adds refs to the group. Members are injected in the order they were
+// added, which for refs added from init functions or package-level
+// variables is the package initialization order. Adding a member again
+// keeps its first position.
+func (g *Group[T]) Add(refs ...*Ref[T]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.members = addMembers(g.members, refs)
+}
+
+// Members returns the refs added to the group, in order
+func (g *Group[T]) Members() []*Ref[T] {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]*Ref[T](nil), g.members...)
+}
+
+// membersIn applies the changes bound in the container and registered in the
+// context chain of ctx to the members of the group, outermost context first
+func (g *Group[T]) membersIn(ctx *Context) []*Ref[T] {
+	members := g.Members()
+	for _, current := range ctx.providerChain() {
+		changes, _ := current.localProviders[g].([]groupChange[T])
+		for _, change := range changes {
+			if change.remove {
+				members = removeMembers(members, change.refs)
+			} else {
+				members = addMembers(members, change.refs)
+			}
+		}
+	}
+	return members
+}
+
+// groupChange adds or removes members of a group for a context subtree
+type groupChange[T any] struct {
+	group  *Group[T]
+	refs   []*Ref[T]
+	remove bool
+}
+
+// register implements Binding
+func (c groupChange[T]) register(ctx *Context) error {
+	if c.group == nil {
+		return &ProviderError{Reason: "group change of a nil group"}
+	}
+	changes, _ := ctx.localProviders[c.group].([]groupChange[T])
+	ctx.localProviders[c.group] = append(changes, c)
+	return nil
+}
+
+// AddToGroup adds refs to group for the subtree the binding is registered in
+func AddToGroup[T any](group *Group[T], refs ...*Ref[T]) Binding {
+	return groupChange[T]{group: group, refs: refs}
+}
+
+// RemoveFromGroup removes refs from group for the subtree the binding is
+// registered in
+func RemoveFromGroup[T any](group *Group[T], refs ...*Ref[T]) Binding {
+	return groupChange[T]{group: group, refs: refs, remove: true}
+}
+
+// InjectAll injects every member of group, in order, taking the members
+// added and removed by local providers into account. Each member is
+// injected like a ref passed to Inject, so it can be overridden as well.
+func InjectAll[T any](ctx *Context, group *Group[T]) []T {
+	members := group.membersIn(ctx)
+	instances := make([]T, len(members))
+	for i, ref := range members {
+		instances[i] = Inject(ctx, ref)
+	}
+	return instances
+}
+
+func addMembers[T any](members, refs []*Ref[T]) []*Ref[T] {
+	for _, ref := range refs {
+		if !containsMember(members, ref) {
+			members = append(members, ref)
+		}
+	}
+	return members
+}
+
+func removeMembers[T any](members, refs []*Ref[T]) []*Ref[T] {
+	kept := make([]*Ref[T], 0, len(members))
+	for _, member := range members {
+		if !containsMember(refs, member) {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
+
+func containsMember[T any](members []*Ref[T], ref *Ref[T]) bool {
+	for _, member := range members {
+		if member == ref {
+			return true
+		}
+	}
+	return false
+}
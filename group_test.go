@@ -0,0 +1,102 @@
+package ioc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInjectAllReturnsMembersInOrder(t *testing.T) {
+	t.Parallel()
+
+	group := NewGroup[string]("routes")
+	usersRef := Provide(func(ctx *Context) string { return "users" })
+	ordersRef := Provide(func(ctx *Context) string { return "orders" })
+	healthRef := Provide(func(ctx *Context) string { return "health" })
+	group.Add(usersRef, ordersRef)
+	group.Add(healthRef, usersRef)
+
+	routes := RunInContainer(NewContainer(), func(ctx *Context) []string {
+		return InjectAll(ctx, group)
+	})
+
+	if strings.Join(routes, ",") != "users,orders,health" {
+		t.Errorf("expected 'users,orders,health', got %v", routes)
+	}
+}
+
+func TestInjectAllOfEmptyGroup(t *testing.T) {
+	t.Parallel()
+
+	group := NewGroup[string]("empty")
+
+	routes := RunInContainer(NewContainer(), func(ctx *Context) []string {
+		return InjectAll(ctx, group)
+	})
+
+	if len(routes) != 0 {
+		t.Errorf("expected no members, got %v", routes)
+	}
+}
+
+func TestGroupMembersChangedByLocalProviders(t *testing.T) {
+	t.Parallel()
+
+	group := NewGroup[string]("handlers")
+	auditRef := Provide(func(ctx *Context) string { return "audit" })
+	mailRef := Provide(func(ctx *Context) string { return "mail" })
+	fakeMailRef := Provide(func(ctx *Context) string { return "fake mail" })
+	metricsRef := Provide(func(ctx *Context) string { return "metrics" })
+	group.Add(auditRef, mailRef)
+
+	innerRef := Provide(func(ctx *Context) []string {
+		return InjectAll(ctx, group)
+	}, ProvideOptions[[]string]{Mode: ModeStandalone, Providers: []any{
+		RemoveFromGroup(group, auditRef),
+		AddToGroup(group, auditRef),
+	}})
+	outerRef := Provide(func(ctx *Context) []string {
+		return append(InjectAll(ctx, group), Inject(ctx, innerRef)...)
+	}, ProvideOptions[[]string]{Providers: []any{
+		RemoveFromGroup(group, mailRef),
+		AddToGroup(group, fakeMailRef, metricsRef),
+	}})
+
+	container := NewContainer()
+	handlers := RunInContainer(container, func(ctx *Context) []string {
+		return Inject(ctx, outerRef)
+	})
+	global := RunInContainer(container, func(ctx *Context) []string {
+		return InjectAll(ctx, group)
+	})
+
+	// The inner subtree sees the outer changes, then moves audit to the end
+	if strings.Join(handlers, ",") != "audit,fake mail,metrics,fake mail,metrics,audit" {
+		t.Errorf("unexpected members %v", handlers)
+	}
+	if strings.Join(global, ",") != "audit,mail" {
+		t.Errorf("expected local changes not to leak, got %v", global)
+	}
+}
+
+func TestGroupMembersHonorOverrides(t *testing.T) {
+	t.Parallel()
+
+	group := NewGroup[string]("migrations")
+	createRef := Provide(func(ctx *Context) string { return "create tables" })
+	group.Add(createRef)
+
+	migrations := RunInContainer(NewContainer(), func(ctx *Context) []string {
+		return InjectAll(ctx, group)
+	}, WithProviders(Value(createRef, "noop")))
+
+	if len(migrations) != 1 || migrations[0] != "noop" {
+		t.Errorf("expected the overridden member, got %v", migrations)
+	}
+}
+
+func TestGroupString(t *testing.T) {
+	group := NewGroup[int]("numbers")
+	if group.String() != `Group[int] "numbers"` || group.Name() != "numbers" {
+		t.Errorf("unexpected description '%s'", group)
+	}
+}
@@ -1,7 +1,13 @@
 package ioc
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"slices"
 	"sync"
 )
 
@@ -13,20 +19,65 @@ const (
 	ModeGlobal Mode = iota
 	// ModeStandalone creates a new instance per context
 	ModeStandalone
+	// ModeScoped creates one instance per scope, shared by every context
+	// below it. Root contexts and contexts created by NewScope are scopes.
+	ModeScoped
+	// ModeTransient creates a new instance on every injection
+	ModeTransient
 )
 
+// String returns the lower-case name of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeGlobal:
+		return "global"
+	case ModeStandalone:
+		return "standalone"
+	case ModeScoped:
+		return "scoped"
+	case ModeTransient:
+		return "transient"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
 // refMarker is an interface to identify Ref types without reflection
 type refMarker interface {
 	isProvideRef() bool
 	getOverride() any
+	describe() RefInfo
+	getMode() Mode
+	injectAny(ctx *Context) (any, error)
+	disposeFunc(instance any) func() error
+	accepts(provider refMarker) bool
 }
 
 // Ref is a reference to a dependency provider
 type Ref[T any] struct {
-	factory   func(ctx *Context) T
+	factory   func(ctx *Context) (T, error)
 	mode      Mode
 	providers []any
 	override  any
+	name      string
+	location  string
+	onStart   func(ctx context.Context, instance T) error
+	onStop    func(ctx context.Context, instance T) error
+	dispose   func(instance T) error
+	// value is the instance of refs created by ProvideValue and the value
+	// bindings, which have no factory
+	value   T
+	isValue bool
+	// token marks refs created by Token, which must be overridden
+	token bool
+	// decorators are registered with Decorate and apply in every context
+	decoratorsMu sync.RWMutex
+	decorators   []func(ctx *Context, inner T) T
+	// overridden is set on the refs that decorate the providers a decorated
+	// ref resolves to, and decoratedProviders holds those refs by mode, see
+	// decoratedProvider
+	overridden         *Ref[T]
+	decoratedProviders map[Mode]*Ref[T]
 }
 
 // isProvideRef implements refMarker interface
@@ -39,38 +90,136 @@ func (r *Ref[T]) getOverride() any {
 	return r.override
 }
 
+// getMode implements refMarker interface
+func (r *Ref[T]) getMode() Mode {
+	return r.mode
+}
+
+// injectAny implements refMarker interface
+func (r *Ref[T]) injectAny(ctx *Context) (any, error) {
+	return InjectE(ctx, r)
+}
+
+// accepts implements refMarker interface
+func (r *Ref[T]) accepts(provider refMarker) bool {
+	_, ok := provider.(*Ref[T])
+	return ok
+}
+
+// describe implements refMarker interface
+func (r *Ref[T]) describe() RefInfo {
+	if r.overridden != nil {
+		return r.overridden.describe()
+	}
+	return RefInfo{
+		Ref:      r,
+		Name:     r.name,
+		Type:     reflect.TypeOf((*T)(nil)).Elem().String(),
+		Location: r.location,
+	}
+}
+
+// Name returns the name given in ProvideOptions, if any
+func (r *Ref[T]) Name() string {
+	return r.name
+}
+
+// String describes the ref by name, type and the place it was provided
+func (r *Ref[T]) String() string {
+	return r.describe().String()
+}
+
 // ProvideOptions configures a provider
 type ProvideOptions[T any] struct {
-	Mode      Mode
+	Mode Mode
+	// Providers are registered for the subtree of this ref's factory. Each
+	// entry is a Binding, or a ref that replaces its Overrides target.
 	Providers []any
+	// Overrides is the *Ref[T] this ref replaces when listed in Providers.
+	// Prefer Override, which the compiler checks.
 	Overrides any
+	// Name identifies the ref in errors and diagnostics
+	Name string
+	// OnStart is run for a resolved global instance by Start
+	OnStart func(ctx context.Context, instance T) error
+	// OnStop is run for a resolved global instance by Stop
+	OnStop func(ctx context.Context, instance T) error
+	// Dispose releases an instance when the context that created it is
+	// closed. Instances implementing io.Closer are closed by default.
+	Dispose func(instance T) error
 }
 
 // Context holds injection state
 type Context struct {
+	container      *Container
 	instances      map[any]any
 	localProviders map[any]any
-	creating       map[any]bool
 	parent         *Context
+	resolving      *resolution
+	disposer       *disposer
+	std            context.Context
+	// scope marks root contexts and contexts created by NewScope, as opposed
+	// to the child contexts created for local providers
+	scope bool
+	// singletons holds the global instances of root contexts created
+	// WithProviders, which are kept apart from those of the container
+	singletons *singletons
+	// detached marks the private contexts of lazy handles taken while
+	// constructing a global singleton, see InjectLazy
+	detached bool
 }
 
-var (
-	globalInstances = make(map[any]any)
-	globalCreating  = make(map[any]bool)
-	globalMu        sync.RWMutex
-)
+// resolution is one link in the chain of refs currently being constructed
+type resolution struct {
+	ref    refMarker
+	call   *call
+	parent *resolution
+}
+
+// call is an in-flight construction of a global instance
+type call struct {
+	ref       refMarker
+	store     *singletons
+	done      chan struct{}
+	instance  any
+	err       error
+	panicked  any
+	lifecycle *lifecycle
+	// waitsFor is the call the owning resolution chain is blocked on, and
+	// blockedAt the innermost link of that chain
+	waitsFor  *call
+	blockedAt *resolution
+}
 
 // Provide creates a new dependency provider
 func Provide[T any](factory func(ctx *Context) T, opts ...ProvideOptions[T]) *Ref[T] {
+	return newRef(func(ctx *Context) (T, error) {
+		return factory(ctx), nil
+	}, opts)
+}
+
+// ProvideE creates a new dependency provider whose factory can fail
+func ProvideE[T any](factory func(ctx *Context) (T, error), opts ...ProvideOptions[T]) *Ref[T] {
+	return newRef(factory, opts)
+}
+
+// newRef builds a ref for the exported Provide functions, recording the
+// location they were called from
+func newRef[T any](factory func(ctx *Context) (T, error), opts []ProvideOptions[T]) *Ref[T] {
 	ref := &Ref[T]{
-		factory: factory,
-		mode:    ModeGlobal,
+		factory:  factory,
+		mode:     ModeGlobal,
+		location: callerLocation(2),
 	}
 
 	if len(opts) > 0 {
 		opt := opts[0]
 		ref.mode = opt.Mode
 		ref.providers = opt.Providers
+		ref.name = opt.Name
+		ref.onStart = opt.OnStart
+		ref.onStop = opt.OnStop
+		ref.dispose = opt.Dispose
 		if opt.Overrides != nil {
 			ref.override = opt.Overrides
 		}
@@ -79,84 +228,276 @@ func Provide[T any](factory func(ctx *Context) T, opts ...ProvideOptions[T]) *Re
 	return ref
 }
 
-// Inject retrieves a dependency from the context
+// Inject retrieves a dependency from the context, panicking if it cannot be
+// constructed
 func Inject[T any](ctx *Context, ref *Ref[T]) T {
-	actualRef := findRefInContext(ctx, ref)
-	isGlobal := actualRef.mode == ModeGlobal
-	useGlobalCache := isGlobal && ctx.parent == nil
+	instance, err := resolve(ctx, ref)
+	if err != nil {
+		panic(err)
+	}
+	return instance
+}
 
-	// Check cache
-	if useGlobalCache {
-		globalMu.RLock()
-		if instance, ok := globalInstances[actualRef]; ok {
-			globalMu.RUnlock()
-			return instance.(T)
+// InjectE retrieves a dependency from the context, returning the error of any
+// failing factory in its dependency chain
+func InjectE[T any](ctx *Context, ref *Ref[T]) (instance T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			// Errors raised by Inject further down the chain are returned,
+			// any other panic is propagated
+			recovered, ok := asInjectionError(r)
+			if !ok {
+				panic(r)
+			}
+			var zero T
+			instance, err = zero, recovered
 		}
-		globalMu.RUnlock()
-	} else {
-		if instance, ok := ctx.instances[actualRef]; ok {
-			return instance.(T)
+	}()
+	return resolve(ctx, ref)
+}
+
+// resolve finds the provider of ref in the context chain and resolves it
+// according to its mode:
+//
+//   - ModeGlobal: the container singleton, constructed from the root context
+//     so it never sees local overrides. If the ref is overridden, the
+//     override is cached in the context that registered it instead, and a
+//     root context created WithProviders caches every global itself.
+//   - ModeStandalone: cached in ctx.
+//   - ModeScoped: cached in the nearest scope, see resolveScoped.
+//   - ModeTransient: never cached.
+//
+// Values are returned as they are, whatever the mode, and tokens that are not
+// overridden fail with ErrNotProvided. Local decorators count as overrides.
+// The decorators of a ref resolving to another provider or to a value are
+// applied by a ref standing in for that provider, see decoratedProvider.
+func resolve[T any](ctx *Context, ref *Ref[T]) (T, error) {
+	actualRef, owner := findRefInContext(ctx, ref)
+	ctx.container.recorder().record(ctx.resolving, ref, actualRef)
+	return resolveProvider(ctx, ref.decorated(ctx, actualRef), owner)
+}
+
+// resolveProvider resolves actualRef, the provider found for a ref in the
+// context chain of ctx, registered in owner
+func resolveProvider[T any](ctx *Context, actualRef *Ref[T], owner *Context) (T, error) {
+	if actualRef.isValue {
+		return actualRef.value, nil
+	}
+	if actualRef.token {
+		var zero T
+		return zero, &ResolutionError{Chain: append(ctx.resolving.chain(), actualRef.describe()), Err: ErrNotProvided}
+	}
+	owner = decoratedOwner(ctx, actualRef, owner)
+	switch actualRef.mode {
+	case ModeGlobal:
+		if owner == nil {
+			root := ctx.root()
+			if root.singletons != nil {
+				return resolveGlobal(ctx.within(root), actualRef, root.singletons)
+			}
+			return resolveGlobal(ctx.within(root), actualRef, ctx.container.globals())
 		}
+		return resolveCached(ctx, actualRef, owner, owner)
+	case ModeScoped:
+		return resolveScoped(ctx, actualRef, owner)
+	case ModeTransient:
+		return resolveTransient(ctx, actualRef)
+	default:
+		return resolveCached(ctx, actualRef, ctx, ctx)
+	}
+}
+
+// resolveCached resolves ref from the instances of cache, constructing it
+// from home if it is missing
+func resolveCached[T any](ctx *Context, ref *Ref[T], cache, home *Context) (T, error) {
+	// Check cache
+	if instance, ok := cache.instances[ref]; ok {
+		return instance.(T), nil
 	}
 
 	// Circular dependency detection
-	if useGlobalCache {
-		globalMu.Lock()
-		if globalCreating[actualRef] {
-			globalMu.Unlock()
-			panic(fmt.Sprintf("Circular dependency detected: Ref(%p)", actualRef))
-		}
-		globalCreating[actualRef] = true
-		globalMu.Unlock()
-		defer func() {
-			globalMu.Lock()
-			delete(globalCreating, actualRef)
-			globalMu.Unlock()
-		}()
-	} else {
-		if ctx.creating[actualRef] {
-			panic(fmt.Sprintf("Circular dependency detected: Ref(%p)", actualRef))
+	if ctx.resolving.contains(ref) {
+		panic(&CircularDependencyError{Path: append(ctx.resolving.chain(), ref.describe())})
+	}
+
+	instance, err := construct(ctx.within(home), ref, nil)
+	if err != nil {
+		return instance, err
+	}
+	cache.instances[ref] = instance
+	ctx.within(cache).trackDisposal(ref, instance)
+	return instance, nil
+}
+
+// resolveTransient constructs a new instance of ref without caching it
+func resolveTransient[T any](ctx *Context, ref *Ref[T]) (T, error) {
+	// Circular dependency detection
+	if ctx.resolving.contains(ref) {
+		panic(&CircularDependencyError{Path: append(ctx.resolving.chain(), ref.describe())})
+	}
+
+	instance, err := construct(ctx, ref, nil)
+	if err != nil {
+		return instance, err
+	}
+	ctx.trackDisposal(ref, instance)
+	return instance, nil
+}
+
+// resolveGlobal resolves a global singleton from store, letting concurrent
+// callers share a single in-flight construction
+func resolveGlobal[T any](ctx *Context, ref *Ref[T], store *singletons) (T, error) {
+	container := ctx.container
+
+	// Check cache
+	container.mu.RLock()
+	if instance, ok := store.instances[ref]; ok {
+		container.mu.RUnlock()
+		return instance.(T), nil
+	}
+	container.mu.RUnlock()
+
+	// Circular dependency detection within this resolution chain
+	if ctx.resolving.contains(ref) {
+		panic(&CircularDependencyError{Path: append(ctx.resolving.chain(), ref.describe())})
+	}
+
+	container.mu.Lock()
+	if instance, ok := store.instances[ref]; ok {
+		container.mu.Unlock()
+		return instance.(T), nil
+	}
+
+	// Another chain is already constructing this ref, wait for its result
+	if c, ok := store.calls[ref]; ok {
+		// Waiting on a call that is itself blocked on this chain would deadlock
+		if path := ctx.resolving.deadlockPath(c); path != nil {
+			container.mu.Unlock()
+			panic(&CircularDependencyError{Path: path})
+		}
+		ctx.resolving.setWaitsFor(c, ctx.resolving)
+		container.mu.Unlock()
+
+		<-c.done
+
+		container.mu.Lock()
+		ctx.resolving.setWaitsFor(nil, nil)
+		container.mu.Unlock()
+
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+		if c.err != nil {
+			var zero T
+			return zero, c.err
+		}
+		return c.instance.(T), nil
+	}
+
+	c := &call{ref: ref, store: store, done: make(chan struct{})}
+	store.calls[ref] = c
+	container.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			c.panicked = recover()
+		}
+		container.mu.Lock()
+		if store.calls[ref] == c {
+			delete(store.calls, ref)
+		}
+		if completed && c.err == nil {
+			store.instances[ref] = c.instance
+			if c.lifecycle != nil {
+				store.lifecycles = append(store.lifecycles, c.lifecycle)
+			}
+		}
+		container.mu.Unlock()
+		close(c.done)
+		if !completed {
+			panic(c.panicked)
+		}
+	}()
+
+	instance, err := construct(ctx, ref, c)
+	c.instance, c.err = instance, err
+	if err == nil {
+		if store.disposer != nil {
+			if release := releaseFunc(ref, instance); release != nil {
+				store.disposer.add(release)
+			}
+		} else {
+			c.lifecycle = newLifecycle(ref, instance)
 		}
-		ctx.creating[actualRef] = true
-		defer delete(ctx.creating, actualRef)
 	}
+	completed = true
+	return instance, err
+}
+
+// construct runs the factory of ref with a context that records it in the
+// resolution chain, and applies the decorators of ref to its instance
+func construct[T any](ctx *Context, ref *Ref[T], c *call) (T, error) {
+	factoryCtx := ctx.enter(ref, c)
+	defer func() {
+		if r := recover(); r != nil {
+			// Errors raised further down the chain already carry their path
+			if err, ok := asInjectionError(r); ok {
+				panic(err)
+			}
+			panic(&FactoryPanicError{
+				Chain: factoryCtx.resolving.chain(),
+				Value: r,
+				Stack: debug.Stack(),
+			})
+		}
+	}()
 
-	// Create instance
 	var instance T
-	if len(actualRef.providers) > 0 {
-		childCtx := createContext(ctx)
-		for _, provider := range actualRef.providers {
-			registerProvider(childCtx, provider)
+	var err error
+	if len(ref.providers) > 0 {
+		childCtx := createContext(factoryCtx.container, factoryCtx)
+		factoryCtx.trackChild(childCtx)
+		for _, provider := range ref.providers {
+			if err := registerProvider(childCtx, provider); err != nil {
+				var zero T
+				return zero, &ResolutionError{Chain: factoryCtx.resolving.chain(), Err: err}
+			}
 		}
-		instance = actualRef.factory(childCtx)
+		instance, err = ref.factory(childCtx)
 	} else {
-		instance = actualRef.factory(ctx)
+		instance, err = ref.factory(factoryCtx)
 	}
 
-	// Cache instance
-	if useGlobalCache {
-		globalMu.Lock()
-		globalInstances[actualRef] = instance
-		globalMu.Unlock()
-	} else {
-		ctx.instances[actualRef] = instance
+	if err != nil {
+		// Keep the chain of the deepest failure if the factory passed it on
+		if _, ok := asInjectionError(err); !ok {
+			err = &ResolutionError{Chain: factoryCtx.resolving.chain(), Err: err}
+		}
+		var zero T
+		return zero, err
 	}
+	instance = decorate(factoryCtx, ref, instance)
+	factoryCtx.container.recorder().construct(ref)
+	return instance, nil
+}
 
-	return instance
+// RunInInjectionContext executes a function within an injection context of
+// the default container
+func RunInInjectionContext[T any](fn func(ctx *Context) T, opts ...ContextOption) T {
+	return RunInContainer(defaultContainer, fn, opts...)
 }
 
-// RunInInjectionContext executes a function within an injection context
-func RunInInjectionContext[T any](fn func(ctx *Context) T) T {
-	ctx := createContext(nil)
-	return fn(ctx)
+// NewContext creates a root injection context of the default container.
+// Close it to dispose of the instances it created.
+func NewContext(opts ...ContextOption) *Context {
+	return defaultContainer.NewContext(opts...)
 }
 
-// ResetGlobalInstances clears all cached global instances (for testing)
+// ResetGlobalInstances clears all cached global instances of the default
+// container (for testing)
 func ResetGlobalInstances() {
-	globalMu.Lock()
-	defer globalMu.Unlock()
-	globalInstances = make(map[any]any)
-	globalCreating = make(map[any]bool)
+	defaultContainer.Reset()
 }
 
 // IsProvideRef checks if a value is a Ref (without reflection)
@@ -168,39 +509,152 @@ func IsProvideRef(value any) bool {
 	return ok
 }
 
-func createContext(parent *Context) *Context {
-	return &Context{
+// callerLocation returns the file:line of the caller skip frames above it
+func callerLocation(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+}
+
+func createContext(container *Container, parent *Context) *Context {
+	ctx := &Context{
+		container:      container,
 		instances:      make(map[any]any),
 		localProviders: make(map[any]any),
-		creating:       make(map[any]bool),
 		parent:         parent,
+		disposer:       &disposer{},
+		scope:          parent == nil,
+	}
+	if parent != nil {
+		ctx.resolving = parent.resolving
+		ctx.std = parent.std
 	}
+	return ctx
+}
+
+// enter returns a view of ctx that shares its state and records ref as being
+// resolved
+func (ctx *Context) enter(ref refMarker, c *call) *Context {
+	view := *ctx
+	view.resolving = &resolution{ref: ref, call: c, parent: ctx.resolving}
+	return &view
 }
 
-func findRefInContext[T any](ctx *Context, ref *Ref[T]) *Ref[T] {
+// within returns a view of target that continues the resolution chain and
+// context.Context of ctx
+func (ctx *Context) within(target *Context) *Context {
+	view := *target
+	view.resolving = ctx.resolving
+	view.std = ctx.std
+	return &view
+}
+
+// root returns the root context ctx descends from
+func (ctx *Context) root() *Context {
 	current := ctx
-	for current != nil {
-		if localRef, ok := current.localProviders[ref]; ok {
-			return localRef.(*Ref[T])
-		}
+	for current.parent != nil {
 		current = current.parent
 	}
-	return ref
+	return current
 }
 
-func registerProvider(ctx *Context, provider any) {
-	// Extract override target from provider
-	override := extractOverride(provider)
-	if override != nil {
-		ctx.localProviders[override] = provider
-	} else {
-		ctx.localProviders[provider] = provider
+func (r *resolution) contains(ref any) bool {
+	for current := r; current != nil; current = current.parent {
+		if current.ref == ref {
+			return true
+		}
 	}
+	return false
 }
 
-func extractOverride(provider any) any {
-	if marker, ok := provider.(refMarker); ok {
-		return marker.getOverride()
+// chain describes the refs being resolved, outermost first
+func (r *resolution) chain() []RefInfo {
+	return r.chainUntil(nil)
+}
+
+// chainUntil describes the links from r up to and including the one owning
+// stop, outermost first
+func (r *resolution) chainUntil(stop *call) []RefInfo {
+	var refs []RefInfo
+	for current := r; current != nil; current = current.parent {
+		refs = append(refs, current.ref.describe())
+		if stop != nil && current.call == stop {
+			break
+		}
+	}
+	for i, j := 0, len(refs)-1; i < j; i, j = i+1, j-1 {
+		refs[i], refs[j] = refs[j], refs[i]
+	}
+	return refs
+}
+
+// deadlockPath returns the cycle that waiting on c would close, or nil if
+// c never waits on this chain
+func (r *resolution) deadlockPath(c *call) []RefInfo {
+	path := r.chain()
+	for w := c; w != nil; w = w.waitsFor {
+		if r.owns(w) {
+			return append(path, w.ref.describe())
+		}
+		if w.blockedAt == nil {
+			return nil
+		}
+		path = append(path, w.blockedAt.chainUntil(w)...)
 	}
 	return nil
 }
+
+func (r *resolution) owns(c *call) bool {
+	for current := r; current != nil; current = current.parent {
+		if current.call == c {
+			return true
+		}
+	}
+	return false
+}
+
+// setWaitsFor marks every call owned by the chain as blocked on c at the
+// given link
+func (r *resolution) setWaitsFor(c *call, at *resolution) {
+	for current := r; current != nil; current = current.parent {
+		if current.call != nil {
+			current.call.waitsFor = c
+			current.call.blockedAt = at
+		}
+	}
+}
+
+// findRefInContext returns the provider registered for ref in the context
+// chain and the context it was registered in. It falls back to the provider
+// bound in the container and nil, then to ref itself and nil.
+func findRefInContext[T any](ctx *Context, ref *Ref[T]) (*Ref[T], *Context) {
+	current := ctx
+	for current != nil {
+		if localRef, ok := current.localProviders[ref]; ok {
+			return localRef.(*Ref[T]), current
+		}
+		current = current.parent
+	}
+	if bound := ctx.container.bound(); bound != nil {
+		if boundRef, ok := bound.localProviders[ref]; ok {
+			return boundRef.(*Ref[T]), nil
+		}
+	}
+	return ref, nil
+}
+
+// providerChain returns the contexts whose local providers apply to ctx,
+// outermost first: the bindings of the container, then the context chain
+func (ctx *Context) providerChain() []*Context {
+	var chain []*Context
+	for current := ctx; current != nil; current = current.parent {
+		chain = append(chain, current)
+	}
+	if bound := ctx.container.bound(); bound != nil {
+		chain = append(chain, bound)
+	}
+	slices.Reverse(chain)
+	return chain
+}
@@ -1,6 +1,7 @@
 package ioc
 
 import (
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -308,10 +309,23 @@ func TestFactoryThrowingError(t *testing.T) {
 	})
 
 	defer func() {
-		if r := recover(); r == nil {
+		r := recover()
+		if r == nil {
 			t.Error("expected panic")
-		} else if r != "Factory error" {
-			t.Errorf("expected 'Factory error', got '%v'", r)
+			return
+		}
+		panicErr, ok := r.(*FactoryPanicError)
+		if !ok {
+			t.Fatalf("expected *FactoryPanicError, got %T", r)
+		}
+		if panicErr.Value != "Factory error" {
+			t.Errorf("expected 'Factory error', got '%v'", panicErr.Value)
+		}
+		if len(panicErr.Chain) != 1 || panicErr.Chain[0].Ref != ref {
+			t.Errorf("expected chain to contain the panicking ref, got %v", panicErr.Chain)
+		}
+		if len(panicErr.Stack) == 0 {
+			t.Error("expected stack trace to be captured")
 		}
 	}()
 
@@ -379,8 +393,22 @@ func TestThreeWayCircularDependency(t *testing.T) {
 	})
 
 	defer func() {
-		if r := recover(); r == nil {
-			t.Error("expected panic for three-way circular dependency")
+		r := recover()
+		cycleErr, ok := r.(*CircularDependencyError)
+		if !ok {
+			t.Fatalf("expected *CircularDependencyError, got %v", r)
+		}
+		expected := []*Ref[string]{aRef, bRef, cRef, aRef}
+		if len(cycleErr.Path) != len(expected) {
+			t.Fatalf("expected path of length %d, got %v", len(expected), cycleErr.Path)
+		}
+		for i, ref := range expected {
+			if cycleErr.Path[i].Ref != ref {
+				t.Errorf("unexpected ref at position %d: %v", i, cycleErr.Path[i])
+			}
+			if cycleErr.Path[i].Type != "string" {
+				t.Errorf("expected type 'string', got '%s'", cycleErr.Path[i].Type)
+			}
 		}
 	}()
 
@@ -813,3 +841,527 @@ func TestMultipleProvidersInSingleRef(t *testing.T) {
 		return nil
 	})
 }
+
+func TestConcurrentGlobalInjectSharesConstruction(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) int32 {
+		time.Sleep(20 * time.Millisecond)
+		return atomic.AddInt32(&counter, 1)
+	})
+
+	var wg sync.WaitGroup
+	results := make([]int32, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			results[idx] = RunInInjectionContext(func(ctx *Context) int32 {
+				return Inject(ctx, ref)
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	if counter != 1 {
+		t.Errorf("expected counter to be 1, got %d", counter)
+	}
+	for _, v := range results {
+		if v != 1 {
+			t.Errorf("expected every caller to get 1, got %d", v)
+		}
+	}
+}
+
+func TestConcurrentGlobalInjectSharesPanic(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) string {
+		if atomic.AddInt32(&counter, 1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			panic("Factory error")
+		}
+		return "retried"
+	})
+
+	var wg sync.WaitGroup
+	panics := make([]any, 5)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			defer func() {
+				panics[idx] = recover()
+			}()
+			RunInInjectionContext(func(ctx *Context) string {
+				return Inject(ctx, ref)
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, p := range panics {
+		panicErr, ok := p.(*FactoryPanicError)
+		if !ok || panicErr.Value != "Factory error" {
+			t.Errorf("expected 'Factory error', got '%v'", p)
+		}
+	}
+
+	// A failed construction is not cached, so the next call retries
+	result := RunInInjectionContext(func(ctx *Context) string {
+		return Inject(ctx, ref)
+	})
+	if result != "retried" {
+		t.Errorf("expected 'retried', got '%s'", result)
+	}
+}
+
+func TestCrossGoroutineCircularDependency(t *testing.T) {
+	ResetGlobalInstances()
+
+	var aRef, bRef *Ref[string]
+	var started sync.WaitGroup
+	started.Add(2)
+
+	aRef = Provide(func(ctx *Context) string {
+		started.Done()
+		started.Wait()
+		return Inject(ctx, bRef)
+	})
+	bRef = Provide(func(ctx *Context) string {
+		started.Done()
+		started.Wait()
+		return Inject(ctx, aRef)
+	})
+
+	var wg sync.WaitGroup
+	panics := make([]any, 2)
+
+	for i, ref := range []*Ref[string]{aRef, bRef} {
+		wg.Add(1)
+		go func(idx int, ref *Ref[string]) {
+			defer wg.Done()
+			defer func() {
+				panics[idx] = recover()
+			}()
+			RunInInjectionContext(func(ctx *Context) string {
+				return Inject(ctx, ref)
+			})
+		}(i, ref)
+	}
+
+	wg.Wait()
+
+	for i, p := range panics {
+		cycleErr, ok := p.(*CircularDependencyError)
+		if !ok {
+			t.Errorf("expected *CircularDependencyError in goroutine %d, got %v", i, p)
+			continue
+		}
+		// The path spans both goroutines and closes on the ref it started from
+		path := cycleErr.Path
+		if len(path) != 3 || path[0].Ref != path[2].Ref || path[0].Ref == path[1].Ref {
+			t.Errorf("expected path X -> Y -> X, got %v", path)
+		}
+	}
+}
+
+func TestCircularDependencyThroughLocalProviders(t *testing.T) {
+	ResetGlobalInstances()
+
+	var aRef, bRef *Ref[string]
+
+	unrelatedRef := Provide(func(ctx *Context) string { return "unused" })
+
+	aRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, bRef)
+	}, ProvideOptions[string]{Providers: []any{unrelatedRef}})
+	bRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, aRef)
+	})
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for circular dependency")
+		}
+	}()
+
+	RunInInjectionContext(func(ctx *Context) string {
+		return Inject(ctx, aRef)
+	})
+}
+
+func TestProvideEReturnsValue(t *testing.T) {
+	ResetGlobalInstances()
+
+	ref := ProvideE(func(ctx *Context) (string, error) {
+		return "value", nil
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		value, err := InjectE(ctx, ref)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if value != "value" {
+			t.Errorf("expected 'value', got '%s'", value)
+		}
+		if Inject(ctx, ref) != "value" {
+			t.Error("expected Inject to return the same value")
+		}
+		return nil
+	})
+}
+
+func TestInjectEReturnsErrorWithChain(t *testing.T) {
+	ResetGlobalInstances()
+
+	errOpen := errors.New("cannot open database")
+
+	dbRef := ProvideE(func(ctx *Context) (string, error) {
+		return "", errOpen
+	})
+	repoRef := ProvideE(func(ctx *Context) (string, error) {
+		db, err := InjectE(ctx, dbRef)
+		if err != nil {
+			return "", err
+		}
+		return "repo:" + db, nil
+	})
+	serviceRef := Provide(func(ctx *Context) string {
+		return "service:" + Inject(ctx, repoRef)
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		value, err := InjectE(ctx, serviceRef)
+		if value != "" {
+			t.Errorf("expected zero value, got '%s'", value)
+		}
+		if !errors.Is(err, errOpen) {
+			t.Fatalf("expected errOpen, got %v", err)
+		}
+
+		var resErr *ResolutionError
+		if !errors.As(err, &resErr) {
+			t.Fatalf("expected *ResolutionError, got %T", err)
+		}
+		if len(resErr.Chain) != 3 || resErr.Chain[0].Ref != serviceRef || resErr.Chain[1].Ref != repoRef || resErr.Chain[2].Ref != dbRef {
+			t.Errorf("expected chain service -> repo -> db, got %v", resErr.Chain)
+		}
+		return nil
+	})
+}
+
+func TestInjectPanicsWithFactoryError(t *testing.T) {
+	ResetGlobalInstances()
+
+	errConfig := errors.New("missing config")
+	ref := ProvideE(func(ctx *Context) (string, error) {
+		return "", errConfig
+	})
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, errConfig) {
+			t.Errorf("expected panic with errConfig, got %v", r)
+		}
+	}()
+
+	RunInInjectionContext(func(ctx *Context) string {
+		return Inject(ctx, ref)
+	})
+}
+
+func TestInjectEReturnsFactoryPanic(t *testing.T) {
+	ResetGlobalInstances()
+
+	errBoom := errors.New("boom")
+	innerRef := Provide(func(ctx *Context) string {
+		panic(errBoom)
+	})
+	outerRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, innerRef)
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		_, err := InjectE(ctx, outerRef)
+
+		var panicErr *FactoryPanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("expected *FactoryPanicError, got %v", err)
+		}
+		if !errors.Is(err, errBoom) {
+			t.Error("expected error to unwrap to the panic value")
+		}
+		if len(panicErr.Chain) != 2 || panicErr.Chain[0].Ref != outerRef || panicErr.Chain[1].Ref != innerRef {
+			t.Errorf("expected chain outer -> inner, got %v", panicErr.Chain)
+		}
+		return nil
+	})
+}
+
+func TestInjectEPropagatesPanicsOutsideFactories(t *testing.T) {
+	ResetGlobalInstances()
+
+	defer func() {
+		if r := recover(); r != "outside" {
+			t.Errorf("expected 'outside', got '%v'", r)
+		}
+	}()
+
+	RunInInjectionContext(func(ctx *Context) any {
+		panic("outside")
+	})
+}
+
+func TestFailedFactoryIsNotCached(t *testing.T) {
+	ResetGlobalInstances()
+
+	for _, mode := range []Mode{ModeGlobal, ModeStandalone} {
+		var attempts int32
+		ref := ProvideE(func(ctx *Context) (int32, error) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				return 0, errors.New("temporary failure")
+			}
+			return attempts, nil
+		}, ProvideOptions[int32]{Mode: mode})
+
+		RunInInjectionContext(func(ctx *Context) any {
+			if _, err := InjectE(ctx, ref); err == nil {
+				t.Errorf("mode %d: expected error on first attempt", mode)
+			}
+			value, err := InjectE(ctx, ref)
+			if err != nil {
+				t.Errorf("mode %d: expected retry to succeed, got %v", mode, err)
+			}
+			if value != 2 {
+				t.Errorf("mode %d: expected 2, got %d", mode, value)
+			}
+			if Inject(ctx, ref) != 2 {
+				t.Errorf("mode %d: expected successful result to be cached", mode)
+			}
+			return nil
+		})
+	}
+}
+
+func TestTransientModeCreatesInstancePerInjection(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	type Builder struct {
+		ID int32
+	}
+
+	ref := Provide(func(ctx *Context) *Builder {
+		return &Builder{ID: atomic.AddInt32(&counter, 1)}
+	}, ProvideOptions[*Builder]{Mode: ModeTransient})
+
+	type Service struct {
+		A, B *Builder
+	}
+	serviceRef := Provide(func(ctx *Context) *Service {
+		return &Service{A: Inject(ctx, ref), B: Inject(ctx, ref)}
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		service := Inject(ctx, serviceRef)
+		if service.A == service.B {
+			t.Error("expected a new instance for every injection within a factory")
+		}
+		if Inject(ctx, ref) == Inject(ctx, ref) {
+			t.Error("expected a new instance for every injection within a context")
+		}
+		if counter != 4 {
+			t.Errorf("expected counter to be 4, got %d", counter)
+		}
+		return nil
+	})
+}
+
+func TestTransientModeHonorsOverrides(t *testing.T) {
+	ResetGlobalInstances()
+
+	bufferRef := Provide(func(ctx *Context) string {
+		return "buffer"
+	}, ProvideOptions[string]{Mode: ModeTransient})
+	testBufferRef := Provide(func(ctx *Context) string {
+		return "test buffer"
+	}, ProvideOptions[string]{Mode: ModeTransient, Overrides: bufferRef})
+
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, bufferRef)
+	}, ProvideOptions[string]{Providers: []any{testBufferRef}})
+
+	result := RunInInjectionContext(func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	if result != "test buffer" {
+		t.Errorf("expected 'test buffer', got '%s'", result)
+	}
+}
+
+func TestTransientCircularDependency(t *testing.T) {
+	ResetGlobalInstances()
+
+	var aRef, bRef *Ref[string]
+	aRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, bRef)
+	}, ProvideOptions[string]{Mode: ModeTransient})
+	bRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, aRef)
+	}, ProvideOptions[string]{Mode: ModeTransient})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		_, err := InjectE(ctx, aRef)
+		var cycleErr *CircularDependencyError
+		if !errors.As(err, &cycleErr) {
+			t.Errorf("expected *CircularDependencyError, got %v", err)
+		}
+		return nil
+	})
+}
+
+func TestGlobalRefInChildContextResolvesToSingleton(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	type Pool struct {
+		ID int32
+	}
+	poolRef := Provide(func(ctx *Context) *Pool {
+		return &Pool{ID: atomic.AddInt32(&counter, 1)}
+	})
+
+	unrelatedRef := Provide(func(ctx *Context) string { return "unrelated" })
+
+	// childRef and grandchildRef inject the pool from provider child contexts
+	grandchildRef := Provide(func(ctx *Context) *Pool {
+		return Inject(ctx, poolRef)
+	}, ProvideOptions[*Pool]{Mode: ModeStandalone, Providers: []any{unrelatedRef}})
+	childRef := Provide(func(ctx *Context) [2]*Pool {
+		return [2]*Pool{Inject(ctx, poolRef), Inject(ctx, grandchildRef)}
+	}, ProvideOptions[[2]*Pool]{Mode: ModeStandalone, Providers: []any{unrelatedRef}})
+
+	first := RunInInjectionContext(func(ctx *Context) [2]*Pool {
+		return Inject(ctx, childRef)
+	})
+	second := RunInInjectionContext(func(ctx *Context) *Pool {
+		return Inject(ctx, poolRef)
+	})
+
+	if first[0] != second || first[1] != second {
+		t.Error("expected every depth to resolve the process-wide singleton")
+	}
+	if counter != 1 {
+		t.Errorf("expected counter to be 1, got %d", counter)
+	}
+}
+
+func TestGlobalSingletonIgnoresOverridesOfInjectingContext(t *testing.T) {
+	ResetGlobalInstances()
+
+	configRef := Provide(func(ctx *Context) string {
+		return "global"
+	})
+	testConfigRef := Provide(func(ctx *Context) string {
+		return "test"
+	}, ProvideOptions[string]{Overrides: configRef})
+
+	clientRef := Provide(func(ctx *Context) string {
+		return "client:" + Inject(ctx, configRef)
+	})
+
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, clientRef) + " " + Inject(ctx, configRef)
+	}, ProvideOptions[string]{Mode: ModeStandalone, Providers: []any{testConfigRef}})
+
+	result := RunInInjectionContext(func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	})
+
+	// The singleton does not depend on which context injected it first
+	if result != "client:global test" {
+		t.Errorf("expected 'client:global test', got '%s'", result)
+	}
+}
+
+func TestOverriddenGlobalIsCachedWhereRegistered(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	configRef := Provide(func(ctx *Context) int32 {
+		return 0
+	})
+	testConfigRef := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&counter, 1)
+	}, ProvideOptions[int32]{Overrides: configRef})
+
+	unrelatedRef := Provide(func(ctx *Context) string { return "unrelated" })
+
+	leafRef := Provide(func(ctx *Context) int32 {
+		return Inject(ctx, configRef)
+	}, ProvideOptions[int32]{Mode: ModeStandalone, Providers: []any{unrelatedRef}})
+	otherLeafRef := Provide(func(ctx *Context) int32 {
+		return Inject(ctx, configRef)
+	}, ProvideOptions[int32]{Mode: ModeStandalone})
+
+	parentRef := Provide(func(ctx *Context) [3]int32 {
+		return [3]int32{Inject(ctx, configRef), Inject(ctx, leafRef), Inject(ctx, otherLeafRef)}
+	}, ProvideOptions[[3]int32]{Mode: ModeStandalone, Providers: []any{testConfigRef}})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		values := Inject(ctx, parentRef)
+		if values != [3]int32{1, 1, 1} {
+			t.Errorf("expected the override to be shared below its registration, got %v", values)
+		}
+		if Inject(ctx, configRef) != 0 {
+			t.Error("expected the global singleton outside the override")
+		}
+
+		// Another registration of the same override gets its own instance
+		if values := Inject(NewContext(), parentRef); values != [3]int32{2, 2, 2} {
+			t.Errorf("expected a new instance for a new registration, got %v", values)
+		}
+		return nil
+	})
+}
+
+func TestNestedOverrideShadowsOuterOverride(t *testing.T) {
+	ResetGlobalInstances()
+
+	for _, mode := range []Mode{ModeGlobal, ModeStandalone} {
+		configRef := Provide(func(ctx *Context) string {
+			return "global"
+		}, ProvideOptions[string]{Mode: mode})
+		outerConfigRef := Provide(func(ctx *Context) string {
+			return "outer"
+		}, ProvideOptions[string]{Mode: mode, Overrides: configRef})
+		innerConfigRef := Provide(func(ctx *Context) string {
+			return "inner"
+		}, ProvideOptions[string]{Mode: mode, Overrides: configRef})
+
+		innerRef := Provide(func(ctx *Context) string {
+			return Inject(ctx, configRef)
+		}, ProvideOptions[string]{Mode: ModeStandalone, Providers: []any{innerConfigRef}})
+		outerRef := Provide(func(ctx *Context) string {
+			return Inject(ctx, configRef) + "/" + Inject(ctx, innerRef)
+		}, ProvideOptions[string]{Mode: ModeStandalone, Providers: []any{outerConfigRef}})
+
+		result := RunInInjectionContext(func(ctx *Context) string {
+			return Inject(ctx, configRef) + "/" + Inject(ctx, outerRef)
+		})
+
+		if result != "global/outer/inner" {
+			t.Errorf("mode %d: expected 'global/outer/inner', got '%s'", mode, result)
+		}
+	}
+}
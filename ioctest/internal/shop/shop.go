@@ -0,0 +1,15 @@
+// Package shop declares a port for the tests of ioctest to stub from another
+// package
+package shop
+
+import ioc "github.com/MunMunMiao/go-ioc"
+
+//go:generate go run github.com/MunMunMiao/go-ioc/cmd/iocstub -type Mailer
+
+// Mailer sends messages to customers
+type Mailer interface {
+	Send(to, body string) error
+}
+
+// MailerRef is bound to an adapter by the composition root
+var MailerRef = ioc.Token[Mailer]("mailer")
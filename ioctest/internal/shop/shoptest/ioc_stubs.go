@@ -0,0 +1,24 @@
+// Code generated by iocstub. DO NOT EDIT.
+
+package shoptest
+
+import (
+	"github.com/MunMunMiao/go-ioc/ioctest"
+	"github.com/MunMunMiao/go-ioc/ioctest/internal/shop"
+)
+
+func init() {
+	ioctest.RegisterFake(func(r *ioctest.Recorder) shop.Mailer {
+		return &mailerStub{recorder: r}
+	})
+}
+
+// mailerStub is a fake Mailer recording its calls
+type mailerStub struct {
+	recorder *ioctest.Recorder
+}
+
+func (s *mailerStub) Send(a0 string, a1 string) error {
+	results := s.recorder.Record("Send", a0, a1)
+	return ioctest.Result[error](results, 0)
+}
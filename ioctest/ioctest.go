@@ -0,0 +1,97 @@
+// Package ioctest provides isolated injection contexts and assertions for
+// tests of code wired with go-ioc.
+//
+// Every context returned by New belongs to a container of its own, so tests
+// using it can run with t.Parallel() without sharing singletons:
+//
+//	func TestCreateOrder(t *testing.T) {
+//		t.Parallel()
+//		ctx := ioctest.New(t, ioc.Value(OrderRepositoryRef, fakeRepo))
+//
+//		uc := ioctest.AssertResolved(t, ctx, CreateOrderUseCaseRef)
+//		// ...
+//		ioctest.AssertConstructedOnce(t, ctx, PricingServiceRef)
+//	}
+package ioctest
+
+import (
+	"context"
+	"testing"
+
+	ioc "github.com/MunMunMiao/go-ioc"
+)
+
+// New returns a root context of a new container with the given providers
+// registered on it, see ioc.WithProviders. The container records its graph,
+// which the construction assertions rely on. The context is closed and the
+// container stopped and reset when the test finishes.
+func New(t testing.TB, providers ...any) *ioc.Context {
+	t.Helper()
+
+	var opts []ioc.ContextOption
+	if len(providers) > 0 {
+		opts = append(opts, ioc.WithProviders(providers...))
+	}
+
+	container := ioc.NewContainer()
+	container.RecordGraph()
+	ctx := newContext(t, container, opts)
+	t.Cleanup(func() {
+		if err := ctx.Close(); err != nil {
+			t.Errorf("ioctest: closing context: %v", err)
+		}
+		if err := container.StopAndReset(context.Background()); err != nil {
+			t.Errorf("ioctest: stopping container: %v", err)
+		}
+	})
+	return ctx
+}
+
+// newContext creates the context, failing the test on an invalid provider
+func newContext(t testing.TB, container *ioc.Container, opts []ioc.ContextOption) (ctx *ioc.Context) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(*ioc.ProviderError)
+			if !ok {
+				panic(r)
+			}
+			t.Fatalf("ioctest: %v", err)
+		}
+	}()
+	return container.NewContext(opts...)
+}
+
+// AssertResolved injects ref, failing the test immediately if it cannot be
+// resolved
+func AssertResolved[T any](t testing.TB, ctx *ioc.Context, ref *ioc.Ref[T]) T {
+	t.Helper()
+	instance, err := ioc.InjectE(ctx, ref)
+	if err != nil {
+		t.Fatalf("expected %s to resolve, got %v", ref, err)
+	}
+	return instance
+}
+
+// AssertNotConstructed checks that the factory of ref has not created an
+// instance in the container of ctx
+func AssertNotConstructed[T any](t testing.TB, ctx *ioc.Context, ref *ioc.Ref[T]) {
+	t.Helper()
+	if n := constructed(ctx, ref); n != 0 {
+		t.Errorf("expected %s not to be constructed, got %d instances", ref, n)
+	}
+}
+
+// AssertConstructedOnce checks that the factory of ref has created exactly
+// one instance in the container of ctx
+func AssertConstructedOnce[T any](t testing.TB, ctx *ioc.Context, ref *ioc.Ref[T]) {
+	t.Helper()
+	if n := constructed(ctx, ref); n != 1 {
+		t.Errorf("expected %s to be constructed once, got %d instances", ref, n)
+	}
+}
+
+func constructed[T any](ctx *ioc.Context, ref *ioc.Ref[T]) int {
+	node, _ := ctx.Container().Graph().Node(ref)
+	return node.Constructed
+}
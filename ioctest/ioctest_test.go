@@ -0,0 +1,161 @@
+package ioctest_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	ioc "github.com/MunMunMiao/go-ioc"
+	"github.com/MunMunMiao/go-ioc/ioctest"
+)
+
+// recorder captures the failures reported by the assertions
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// run calls fn with a recorder, on its own goroutine so Fatalf can stop it
+func run(t *testing.T, fn func(r *recorder)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r
+}
+
+type closer struct{ closed *bool }
+
+func (c closer) Close() error {
+	*c.closed = true
+	return nil
+}
+
+func TestNewIsolatesContainers(t *testing.T) {
+	t.Parallel()
+
+	var counter int
+	ref := ioc.Provide(func(ctx *ioc.Context) *int {
+		counter++
+		value := counter
+		return &value
+	})
+
+	first := ioctest.AssertResolved(t, ioctest.New(t), ref)
+	second := ioctest.AssertResolved(t, ioctest.New(t), ref)
+
+	if first == second {
+		t.Error("expected every context to have its own singletons")
+	}
+}
+
+func TestNewRegistersProviders(t *testing.T) {
+	t.Parallel()
+
+	configRef := ioc.Provide(func(ctx *ioc.Context) string { return "production" })
+	serviceRef := ioc.Provide(func(ctx *ioc.Context) string {
+		return "service:" + ioc.Inject(ctx, configRef)
+	})
+
+	ctx := ioctest.New(t, ioc.Value(configRef, "test"))
+
+	if service := ioctest.AssertResolved(t, ctx, serviceRef); service != "service:test" {
+		t.Errorf("expected 'service:test', got '%s'", service)
+	}
+	ioctest.AssertNotConstructed(t, ctx, configRef)
+}
+
+func TestNewClosesContextOnCleanup(t *testing.T) {
+	t.Parallel()
+
+	closed := false
+	t.Run("inner", func(t *testing.T) {
+		ref := ioc.Provide(func(ctx *ioc.Context) closer {
+			return closer{closed: &closed}
+		}, ioc.ProvideOptions[closer]{Mode: ioc.ModeStandalone})
+		ioctest.AssertResolved(t, ioctest.New(t), ref)
+	})
+
+	if !closed {
+		t.Error("expected the instance to be disposed when the test finished")
+	}
+}
+
+func TestNewFailsOnInvalidProvider(t *testing.T) {
+	t.Parallel()
+
+	r := run(t, func(r *recorder) {
+		ioctest.New(r, "not a ref")
+		r.Errorf("unreachable")
+	})
+
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "Invalid provider") {
+		t.Errorf("expected the test to fail with the provider error, got %v", r.errors)
+	}
+}
+
+func TestAssertResolvedFailsOnError(t *testing.T) {
+	t.Parallel()
+
+	ref := ioc.ProvideE(func(ctx *ioc.Context) (string, error) {
+		return "", fmt.Errorf("unavailable")
+	}, ioc.ProvideOptions[string]{Name: "Broken"})
+
+	r := run(t, func(r *recorder) {
+		ioctest.AssertResolved(r, ioctest.New(t), ref)
+		r.Errorf("unreachable")
+	})
+
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], `"Broken"`) || !strings.Contains(r.errors[0], "unavailable") {
+		t.Errorf("expected the failure to name the ref and its error, got %v", r.errors)
+	}
+}
+
+func TestConstructionAssertions(t *testing.T) {
+	t.Parallel()
+
+	lazyRef := ioc.Provide(func(ctx *ioc.Context) int { return 1 })
+	transientRef := ioc.Provide(func(ctx *ioc.Context) int {
+		return 2
+	}, ioc.ProvideOptions[int]{Mode: ioc.ModeTransient})
+
+	ctx := ioctest.New(t)
+	ioctest.AssertResolved(t, ctx, transientRef)
+	ioctest.AssertResolved(t, ctx, transientRef)
+
+	ioctest.AssertNotConstructed(t, ctx, lazyRef)
+
+	r := run(t, func(r *recorder) {
+		ioctest.AssertConstructedOnce(r, ctx, transientRef)
+		ioctest.AssertConstructedOnce(r, ctx, lazyRef)
+		ioctest.AssertNotConstructed(r, ctx, transientRef)
+	})
+
+	if len(r.errors) != 3 {
+		t.Fatalf("expected 3 failures, got %v", r.errors)
+	}
+	if !strings.Contains(r.errors[0], "constructed once, got 2 instances") {
+		t.Errorf("unexpected failure '%s'", r.errors[0])
+	}
+	if !strings.Contains(r.errors[1], "constructed once, got 0 instances") {
+		t.Errorf("unexpected failure '%s'", r.errors[1])
+	}
+	if !strings.Contains(r.errors[2], "not to be constructed, got 2 instances") {
+		t.Errorf("unexpected failure '%s'", r.errors[2])
+	}
+}
@@ -0,0 +1,139 @@
+package ioctest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	ioc "github.com/MunMunMiao/go-ioc"
+)
+
+// Call is a call made to a fake
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// Recorder is the state behind a fake: the calls made to it and the results
+// configured for its methods. Fakes generated by iocstub report every call
+// to Record.
+type Recorder struct {
+	mu       sync.Mutex
+	calls    []Call
+	results  map[string][]any
+	handlers map[string]func(args []any) []any
+}
+
+// Record notes a call and returns the results configured for the method,
+// which are nil if there are none
+func (r *Recorder) Record(method string, args ...any) []any {
+	r.mu.Lock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+	handler := r.handlers[method]
+	results := r.results[method]
+	r.mu.Unlock()
+
+	if handler != nil {
+		return handler(args)
+	}
+	return results
+}
+
+// Returns makes every call to method return results. Missing and nil results
+// are returned as zero values.
+func (r *Recorder) Returns(method string, results ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.results == nil {
+		r.results = make(map[string][]any)
+	}
+	r.results[method] = results
+	delete(r.handlers, method)
+}
+
+// Handle makes calls to method return the results of fn, called with the
+// arguments of each call
+func (r *Recorder) Handle(method string, fn func(args []any) []any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handlers == nil {
+		r.handlers = make(map[string]func(args []any) []any)
+	}
+	r.handlers[method] = fn
+	delete(r.results, method)
+}
+
+// Calls returns the calls made to method in order, or every call if method
+// is empty
+func (r *Recorder) Calls(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var calls []Call
+	for _, call := range r.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// CallCount returns the number of calls made to method
+func (r *Recorder) CallCount(method string) int {
+	return len(r.Calls(method))
+}
+
+// Result returns results[i] as a T, or the zero value if it is missing or nil.
+// Generated fakes use it to convert the results of Record.
+func Result[T any](results []any, i int) T {
+	var zero T
+	if i >= len(results) || results[i] == nil {
+		return zero
+	}
+	return results[i].(T)
+}
+
+// Fake is a stub for an interface-typed ref
+type Fake[I any] struct {
+	*Recorder
+	// Value is the fake implementation of I
+	Value I
+	ref   *ioc.Ref[I]
+}
+
+// Binding binds the ref of the fake to its Value, for use with New or in
+// ProvideOptions.Providers
+func (f *Fake[I]) Binding() ioc.Binding {
+	return ioc.Value(f.ref, f.Value)
+}
+
+var (
+	fakesMu sync.RWMutex
+	fakes   = make(map[reflect.Type]any)
+)
+
+// RegisterFake registers the constructor of the fake implementation of the
+// interface I. Fakes generated by iocstub register themselves.
+func RegisterFake[I any](newFake func(r *Recorder) I) {
+	fakesMu.Lock()
+	defer fakesMu.Unlock()
+	fakes[reflect.TypeOf((*I)(nil)).Elem()] = newFake
+}
+
+// Stub creates a fake for ref, whose type must be an interface with a fake
+// registered by RegisterFake. It panics otherwise.
+func Stub[I any](ref *ioc.Ref[I]) *Fake[I] {
+	typ := reflect.TypeOf((*I)(nil)).Elem()
+	if typ.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("ioctest: cannot stub %s, %s is not an interface", ref, typ))
+	}
+
+	fakesMu.RLock()
+	newFake, ok := fakes[typ].(func(r *Recorder) I)
+	fakesMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("ioctest: no fake registered for %s, generate one with iocstub", typ))
+	}
+
+	recorder := &Recorder{}
+	return &Fake[I]{Recorder: recorder, Value: newFake(recorder), ref: ref}
+}
@@ -0,0 +1,152 @@
+package ioctest_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	ioc "github.com/MunMunMiao/go-ioc"
+	"github.com/MunMunMiao/go-ioc/ioctest"
+	"github.com/MunMunMiao/go-ioc/ioctest/internal/shop"
+	_ "github.com/MunMunMiao/go-ioc/ioctest/internal/shop/shoptest"
+)
+
+type Order struct{ ID string }
+
+type OrderRepository interface {
+	Save(order *Order) error
+	FindByID(id string) (*Order, error)
+}
+
+// The fake below is what iocstub generates for OrderRepository
+func init() {
+	ioctest.RegisterFake(func(r *ioctest.Recorder) OrderRepository {
+		return &orderRepositoryStub{recorder: r}
+	})
+}
+
+type orderRepositoryStub struct {
+	recorder *ioctest.Recorder
+}
+
+func (s *orderRepositoryStub) FindByID(a0 string) (*Order, error) {
+	results := s.recorder.Record("FindByID", a0)
+	return ioctest.Result[*Order](results, 0), ioctest.Result[error](results, 1)
+}
+
+func (s *orderRepositoryStub) Save(a0 *Order) error {
+	results := s.recorder.Record("Save", a0)
+	return ioctest.Result[error](results, 0)
+}
+
+var orderRepositoryRef = ioc.Provide(func(ctx *ioc.Context) OrderRepository {
+	panic("the real repository is not available in tests")
+})
+
+var confirmOrderRef = ioc.Provide(func(ctx *ioc.Context) func(id string) error {
+	repo := ioc.Inject(ctx, orderRepositoryRef)
+	return func(id string) error {
+		order, err := repo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		return repo.Save(order)
+	}
+})
+
+func TestStubRecordsCallsAndReturnsResults(t *testing.T) {
+	t.Parallel()
+
+	repo := ioctest.Stub(orderRepositoryRef)
+	order := &Order{ID: "ORD-1"}
+	repo.Returns("FindByID", order)
+
+	ctx := ioctest.New(t, repo.Binding())
+	confirm := ioctest.AssertResolved(t, ctx, confirmOrderRef)
+
+	if err := confirm("ORD-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if repo.CallCount("FindByID") != 1 || repo.Calls("FindByID")[0].Args[0] != "ORD-1" {
+		t.Errorf("expected FindByID to be called with 'ORD-1', got %v", repo.Calls("FindByID"))
+	}
+	if saves := repo.Calls("Save"); len(saves) != 1 || saves[0].Args[0] != order {
+		t.Errorf("expected the found order to be saved, got %v", saves)
+	}
+	if calls := repo.Calls(""); len(calls) != 2 || calls[0].Method != "FindByID" || calls[1].Method != "Save" {
+		t.Errorf("expected calls in order, got %v", calls)
+	}
+	ioctest.AssertNotConstructed(t, ctx, orderRepositoryRef)
+}
+
+func TestStubReturnsZeroValuesByDefault(t *testing.T) {
+	t.Parallel()
+
+	repo := ioctest.Stub(orderRepositoryRef)
+
+	order, err := repo.Value.FindByID("ORD-1")
+	if order != nil || err != nil {
+		t.Errorf("expected zero values, got %v, %v", order, err)
+	}
+}
+
+func TestStubHandle(t *testing.T) {
+	t.Parallel()
+
+	repo := ioctest.Stub(orderRepositoryRef)
+	repo.Handle("FindByID", func(args []any) []any {
+		if args[0] == "missing" {
+			return []any{nil, errors.New("not found")}
+		}
+		return []any{&Order{ID: args[0].(string)}}
+	})
+
+	if order, _ := repo.Value.FindByID("ORD-2"); order == nil || order.ID != "ORD-2" {
+		t.Errorf("expected the handler's order, got %v", order)
+	}
+	if _, err := repo.Value.FindByID("missing"); err == nil || err.Error() != "not found" {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+}
+
+func TestStubOfInterfaceFromAnotherPackage(t *testing.T) {
+	t.Parallel()
+
+	mailer := ioctest.Stub(shop.MailerRef)
+	mailer.Returns("Send", errors.New("bounced"))
+	notifyRef := ioc.Provide(func(ctx *ioc.Context) error {
+		return ioc.Inject(ctx, shop.MailerRef).Send("ada@example.com", "shipped")
+	}, ioc.ProvideOptions[error]{Mode: ioc.ModeTransient})
+
+	ctx := ioctest.New(t, mailer.Binding())
+	if err := ioctest.AssertResolved(t, ctx, notifyRef); err == nil || err.Error() != "bounced" {
+		t.Errorf("expected the configured error, got %v", err)
+	}
+	if sends := mailer.Calls("Send"); len(sends) != 1 || sends[0].Args[0] != "ada@example.com" {
+		t.Errorf("expected one message to ada@example.com, got %v", sends)
+	}
+}
+
+func TestStubPanicsWithoutFake(t *testing.T) {
+	t.Parallel()
+
+	type Unregistered interface{ Do() }
+	unregisteredRef := ioc.Provide(func(ctx *ioc.Context) Unregistered { return nil })
+	concreteRef := ioc.Provide(func(ctx *ioc.Context) *Order { return nil })
+
+	for name, stub := range map[string]func(){
+		"no fake":       func() { ioctest.Stub(unregisteredRef) },
+		"not interface": func() { ioctest.Stub(concreteRef) },
+	} {
+		func() {
+			defer func() {
+				message, _ := recover().(string)
+				if !strings.HasPrefix(message, "ioctest: ") {
+					t.Errorf("%s: expected Stub to panic, got '%s'", name, message)
+				}
+			}()
+			stub()
+		}()
+	}
+}
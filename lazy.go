@@ -0,0 +1,86 @@
+package ioc
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Lazy is a handle on a ref that is injected on first use, see InjectLazy
+type Lazy[T any] struct {
+	ctx      *Context
+	ref      *Ref[T]
+	mu       sync.Mutex
+	done     atomic.Bool
+	instance T
+}
+
+// InjectLazy returns a handle that injects ref on its first Get and returns
+// the same instance afterwards. The ref is resolved from ctx, so the
+// overrides in effect where InjectLazy was called still apply, however much
+// later Get is called.
+//
+// Because nothing is constructed until Get is called, a factory can take a
+// lazy handle on a ref that depends back on it. A handle taken while
+// constructing a global singleton may be used from any goroutine long after
+// the context it was taken from is gone, so it resolves from a private copy
+// of that context. Like the other instances created for a singleton, the
+// ones it creates live as long as the singleton and are not disposed of.
+func InjectLazy[T any](ctx *Context, ref *Ref[T]) *Lazy[T] {
+	if ctx.resolving.global() != nil {
+		ctx = ctx.detach()
+	}
+	return &Lazy[T]{ctx: ctx, ref: ref}
+}
+
+// Get returns the instance of the ref, injecting it on the first call.
+// Concurrent first calls share a single resolution; a failed one panics like
+// Inject and is retried by the next call.
+//
+// ctx is the context of the caller: a factory passes the one it was given,
+// so that a call made while the ref is being resolved, which can only come
+// from its own dependencies, panics with a *CircularDependencyError instead
+// of waiting for itself. Code running outside of any factory passes nil.
+func (l *Lazy[T]) Get(ctx *Context) T {
+	if l.done.Load() {
+		return l.instance
+	}
+
+	view := *l.ctx
+	view.resolving = nil
+	if ctx != nil {
+		view.resolving = ctx.resolving
+	}
+	actualRef, _ := findRefInContext(&view, l.ref)
+	if view.resolving.contains(l.ref.decorated(&view, actualRef)) {
+		panic(&CircularDependencyError{Path: append(view.resolving.chain(), l.ref.describe())})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.done.Load() {
+		instance, err := resolve(&view, l.ref)
+		if err != nil {
+			panic(err)
+		}
+		l.instance = instance
+		l.done.Store(true)
+	}
+	return l.instance
+}
+
+// detach copies the context chain of ctx with empty instance caches. The
+// copies share the local providers, which are never changed once registered,
+// and dispose of nothing.
+func (ctx *Context) detach() *Context {
+	var parent *Context
+	if ctx.parent != nil {
+		parent = ctx.parent.detach()
+	}
+	detached := createContext(ctx.container, parent)
+	detached.localProviders = ctx.localProviders
+	detached.scope = ctx.scope
+	detached.singletons = ctx.singletons
+	detached.detached = true
+	detached.std = ctx.std
+	return detached
+}
@@ -0,0 +1,233 @@
+package ioc
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInjectLazyResolvesOnFirstCall(t *testing.T) {
+	t.Parallel()
+
+	var constructed int32
+	expensiveRef := Provide(func(ctx *Context) *int32 {
+		atomic.AddInt32(&constructed, 1)
+		return &constructed
+	})
+
+	lazy := RunInContainer(NewContainer(), func(ctx *Context) *Lazy[*int32] {
+		return InjectLazy(ctx, expensiveRef)
+	})
+
+	if constructed != 0 {
+		t.Fatalf("expected nothing to be constructed before the first call, got %d", constructed)
+	}
+	if lazy.Get(nil) != lazy.Get(nil) || constructed != 1 {
+		t.Errorf("expected one construction shared by every call, got %d", constructed)
+	}
+}
+
+func TestInjectLazyAllowsBackReferences(t *testing.T) {
+	t.Parallel()
+
+	type Child struct{ parent any }
+	type Parent struct{ child *Lazy[*Child] }
+
+	var parentRef *Ref[*Parent]
+	childRef := Provide(func(ctx *Context) *Child {
+		return &Child{parent: Inject(ctx, parentRef)}
+	})
+	parentRef = Provide(func(ctx *Context) *Parent {
+		return &Parent{child: InjectLazy(ctx, childRef)}
+	})
+
+	parent := RunInContainer(NewContainer(), func(ctx *Context) *Parent {
+		return Inject(ctx, parentRef)
+	})
+
+	if child := parent.child.Get(nil); child.parent != parent {
+		t.Error("expected the child to see the parent singleton")
+	}
+}
+
+func TestInjectLazyDuringConstructionDetectsCycles(t *testing.T) {
+	t.Parallel()
+
+	var aRef *Ref[string]
+	bRef := Provide(func(ctx *Context) string {
+		return "b:" + Inject(ctx, aRef)
+	})
+	aRef = Provide(func(ctx *Context) string {
+		return "a:" + InjectLazy(ctx, bRef).Get(ctx)
+	})
+
+	err := RunInContainer(NewContainer(), func(ctx *Context) error {
+		_, err := InjectE(ctx, aRef)
+		return err
+	})
+
+	var cycleErr *CircularDependencyError
+	if !errors.As(err, &cycleErr) {
+		t.Errorf("expected *CircularDependencyError, got %v", err)
+	}
+}
+
+func TestInjectLazyKeepsOverridesOfCapturingContext(t *testing.T) {
+	t.Parallel()
+
+	configRef := Provide(func(ctx *Context) string { return "production" })
+	serviceRef := Provide(func(ctx *Context) *Lazy[string] {
+		return InjectLazy(ctx, configRef)
+	}, ProvideOptions[*Lazy[string]]{Providers: []any{Value(configRef, "test")}})
+
+	container := NewContainer()
+	config := RunInContainer(container, func(ctx *Context) *Lazy[string] {
+		return Inject(ctx, serviceRef)
+	})
+
+	if value := config.Get(nil); value != "test" {
+		t.Errorf("expected the override of the capturing context, got '%s'", value)
+	}
+	if value := RunInContainer(container, func(ctx *Context) string { return Inject(ctx, configRef) }); value != "production" {
+		t.Errorf("expected the singleton to be untouched, got '%s'", value)
+	}
+}
+
+func TestInjectLazySharesConcurrentFirstCall(t *testing.T) {
+	t.Parallel()
+
+	var constructed int32
+	ref := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&constructed, 1)
+	}, ProvideOptions[int32]{Mode: ModeTransient})
+
+	lazy := InjectLazy(NewContainer().NewContext(), ref)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value := lazy.Get(nil); value != 1 {
+				t.Errorf("expected every call to get the first instance, got %d", value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if constructed != 1 {
+		t.Errorf("expected a single construction, got %d", constructed)
+	}
+}
+
+func TestInjectLazyRetriesFailures(t *testing.T) {
+	t.Parallel()
+
+	var attempts int
+	ref := ProvideE(func(ctx *Context) (string, error) {
+		attempts++
+		if attempts == 1 {
+			return "", errors.New("unavailable")
+		}
+		return "connected", nil
+	})
+
+	lazy := InjectLazy(NewContainer().NewContext(), ref)
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*ResolutionError); !ok {
+				t.Error("expected the first call to panic with *ResolutionError")
+			}
+		}()
+		lazy.Get(nil)
+	}()
+
+	if value := lazy.Get(nil); value != "connected" {
+		t.Errorf("expected the second call to retry, got '%s'", value)
+	}
+}
+
+func TestInjectLazyCalledFromItsOwnResolutionDetectsCycles(t *testing.T) {
+	t.Parallel()
+
+	type Parent struct{ child *Lazy[string] }
+
+	var parentRef *Ref[*Parent]
+	childRef := Provide(func(ctx *Context) string {
+		parent := Inject(ctx, parentRef)
+		return "child of " + parent.child.Get(ctx)
+	}, ProvideOptions[string]{Name: "Child"})
+	parentRef = Provide(func(ctx *Context) *Parent {
+		return &Parent{child: InjectLazy(ctx, childRef)}
+	})
+
+	parent := RunInContainer(NewContainer(), func(ctx *Context) *Parent {
+		return Inject(ctx, parentRef)
+	})
+
+	done := make(chan any)
+	go func() {
+		defer func() { done <- recover() }()
+		parent.child.Get(nil)
+	}()
+
+	select {
+	case recovered := <-done:
+		cycleErr, ok := recovered.(*CircularDependencyError)
+		if !ok {
+			t.Fatalf("expected *CircularDependencyError, got %v", recovered)
+		}
+		if path := cycleErr.Path; len(path) < 2 || path[len(path)-1].Name != "Child" {
+			t.Errorf("expected the path to end with the child, got %v", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("calling the handle from its own resolution deadlocked")
+	}
+}
+
+func TestInjectLazyOfSingletonDoesNotShareContext(t *testing.T) {
+	t.Parallel()
+
+	handleRef := Provide(func(ctx *Context) string {
+		return "handle"
+	}, ProvideOptions[string]{Mode: ModeStandalone})
+	otherRef := Provide(func(ctx *Context) int {
+		return 1
+	}, ProvideOptions[int]{Mode: ModeStandalone})
+	singletonRef := Provide(func(ctx *Context) *Lazy[string] {
+		return InjectLazy(ctx, handleRef)
+	})
+
+	ctx := NewContainer().NewContext()
+	lazy := Inject(ctx, singletonRef)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			lazy.Get(nil)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			Inject(ctx.NewScope(), otherRef)
+			Inject(ctx, otherRef)
+		}
+	}()
+	wg.Wait()
+
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ctx.instances[handleRef]; ok {
+		t.Error("expected the handle of the singleton not to cache in the context it was taken from")
+	}
+	if value := lazy.Get(nil); value != "handle" {
+		t.Errorf("expected the handle to keep working after the context is closed, got '%s'", value)
+	}
+}
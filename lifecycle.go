@@ -0,0 +1,148 @@
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// lifecycle tracks the hooks of a resolved global instance
+type lifecycle struct {
+	ref     refMarker
+	start   func(ctx context.Context) error
+	stop    func(ctx context.Context) error
+	running bool
+}
+
+// newLifecycle binds the hooks of ref to instance, returning nil if the ref
+// has no hooks
+func newLifecycle[T any](ref *Ref[T], instance T) *lifecycle {
+	if ref.onStart == nil && ref.onStop == nil {
+		return nil
+	}
+	// Instances without an OnStart hook are running as soon as they exist
+	l := &lifecycle{ref: ref, running: ref.onStart == nil}
+	if ref.onStart != nil {
+		l.start = func(ctx context.Context) error {
+			return ref.onStart(ctx, instance)
+		}
+	}
+	if ref.onStop != nil {
+		l.stop = func(ctx context.Context) error {
+			return ref.onStop(ctx, instance)
+		}
+	}
+	return l
+}
+
+// Start runs the OnStart hooks of the default container
+func Start(ctx context.Context) error {
+	return defaultContainer.Start(ctx)
+}
+
+// Stop runs the OnStop hooks of the default container
+func Stop(ctx context.Context) error {
+	return defaultContainer.Stop(ctx)
+}
+
+// StopAndResetGlobalInstances runs Stop and then clears all cached global
+// instances of the default container, even if some hooks failed
+func StopAndResetGlobalInstances(ctx context.Context) error {
+	return defaultContainer.StopAndReset(ctx)
+}
+
+// Start runs the OnStart hooks of every resolved global instance that has not
+// been started yet, in creation order. If a hook fails, the instances started
+// by this call are stopped again in reverse order and all errors are returned.
+// A hook still running when ctx is done is abandoned and reported as failed.
+func (c *Container) Start(ctx context.Context) error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	var started []*lifecycle
+	for _, l := range c.snapshotLifecycles() {
+		if l.running {
+			continue
+		}
+		if l.start != nil {
+			if err := runHook(ctx, l.start); err != nil {
+				errs := []error{&LifecycleError{Ref: l.ref.describe(), Hook: "OnStart", Err: err}}
+				for i := len(started) - 1; i >= 0; i-- {
+					errs = append(errs, stopLifecycle(ctx, started[i]))
+				}
+				return errors.Join(errs...)
+			}
+		}
+		l.running = true
+		started = append(started, l)
+	}
+	return nil
+}
+
+// Stop runs the OnStop hooks of every running global instance in reverse
+// creation order, so an instance is stopped before the dependencies it
+// injected. Instances without an OnStart hook count as running once resolved.
+// All hooks are run even if some fail, and their errors are joined.
+func (c *Container) Stop(ctx context.Context) error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	lifecycles := c.snapshotLifecycles()
+	var errs []error
+	for i := len(lifecycles) - 1; i >= 0; i-- {
+		l := lifecycles[i]
+		if l.running {
+			errs = append(errs, stopLifecycle(ctx, l))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopAndReset runs Stop and then clears all cached global instances, even if
+// some hooks failed
+func (c *Container) StopAndReset(ctx context.Context) error {
+	err := c.Stop(ctx)
+	c.Reset()
+	return err
+}
+
+func (c *Container) snapshotLifecycles() []*lifecycle {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]*lifecycle(nil), c.singletons.lifecycles...)
+}
+
+func stopLifecycle(ctx context.Context, l *lifecycle) error {
+	l.running = false
+	if l.stop == nil {
+		return nil
+	}
+	if err := runHook(ctx, l.stop); err != nil {
+		return &LifecycleError{Ref: l.ref.describe(), Hook: "OnStop", Err: err}
+	}
+	return nil
+}
+
+// runHook runs hook until it returns or ctx is done
+func runHook(ctx context.Context, hook func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("hook panicked: %v", r)
+			}
+		}()
+		done <- hook(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,214 @@
+package ioc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartAndStopRunHooksInDependencyOrder(t *testing.T) {
+	ResetGlobalInstances()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+
+	dbRef := Provide(func(ctx *Context) string {
+		return "db"
+	}, ProvideOptions[string]{
+		OnStart: func(ctx context.Context, db string) error {
+			record("start " + db)
+			return nil
+		},
+		OnStop: func(ctx context.Context, db string) error {
+			record("stop " + db)
+			return nil
+		},
+	})
+
+	serverRef := Provide(func(ctx *Context) string {
+		return "server(" + Inject(ctx, dbRef) + ")"
+	}, ProvideOptions[string]{
+		OnStart: func(ctx context.Context, server string) error {
+			record("start " + server)
+			return nil
+		},
+		OnStop: func(ctx context.Context, server string) error {
+			record("stop " + server)
+			return nil
+		},
+	})
+
+	RunInInjectionContext(func(ctx *Context) string {
+		return Inject(ctx, serverRef)
+	})
+
+	if err := Start(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// A second Start does not restart running instances
+	if err := Start(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := Stop(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []string{"start db", "start server(db)", "stop server(db)", "stop db"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, events)
+			break
+		}
+	}
+}
+
+func TestStopWithoutStartStopsInstancesWithoutOnStart(t *testing.T) {
+	ResetGlobalInstances()
+
+	var stopped []string
+	poolRef := Provide(func(ctx *Context) string {
+		return "pool"
+	}, ProvideOptions[string]{
+		OnStop: func(ctx context.Context, pool string) error {
+			stopped = append(stopped, pool)
+			return nil
+		},
+	})
+	consumerRef := Provide(func(ctx *Context) string {
+		return "consumer"
+	}, ProvideOptions[string]{
+		OnStart: func(ctx context.Context, consumer string) error {
+			return nil
+		},
+		OnStop: func(ctx context.Context, consumer string) error {
+			stopped = append(stopped, consumer)
+			return nil
+		},
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		Inject(ctx, poolRef)
+		Inject(ctx, consumerRef)
+		return nil
+	})
+
+	if err := Stop(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(stopped) != 1 || stopped[0] != "pool" {
+		t.Errorf("expected only 'pool' to be stopped, got %v", stopped)
+	}
+
+	// Stopped instances are not stopped twice
+	if err := Stop(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(stopped) != 1 {
+		t.Errorf("expected no further stops, got %v", stopped)
+	}
+}
+
+func TestStartFailureRollsBackStartedInstances(t *testing.T) {
+	ResetGlobalInstances()
+
+	errStart := errors.New("cannot connect")
+	var stopped []string
+
+	firstRef := Provide(func(ctx *Context) string {
+		return "first"
+	}, ProvideOptions[string]{
+		OnStart: func(ctx context.Context, first string) error { return nil },
+		OnStop: func(ctx context.Context, first string) error {
+			stopped = append(stopped, first)
+			return nil
+		},
+	})
+	failingRef := Provide(func(ctx *Context) string {
+		return "failing"
+	}, ProvideOptions[string]{
+		Name:    "failing",
+		OnStart: func(ctx context.Context, failing string) error { return errStart },
+		OnStop: func(ctx context.Context, failing string) error {
+			stopped = append(stopped, failing)
+			return nil
+		},
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		Inject(ctx, firstRef)
+		Inject(ctx, failingRef)
+		return nil
+	})
+
+	err := Start(context.Background())
+	if !errors.Is(err, errStart) {
+		t.Fatalf("expected errStart, got %v", err)
+	}
+
+	var lifecycleErr *LifecycleError
+	if !errors.As(err, &lifecycleErr) {
+		t.Fatalf("expected *LifecycleError, got %T", err)
+	}
+	if lifecycleErr.Hook != "OnStart" || lifecycleErr.Ref.Ref != failingRef {
+		t.Errorf("unexpected error details: %v", lifecycleErr)
+	}
+	if len(stopped) != 1 || stopped[0] != "first" {
+		t.Errorf("expected 'first' to be rolled back, got %v", stopped)
+	}
+}
+
+func TestStopAggregatesErrorsAndHonorsTimeout(t *testing.T) {
+	ResetGlobalInstances()
+
+	errFirst := errors.New("first failed")
+	firstRef := Provide(func(ctx *Context) int {
+		return 1
+	}, ProvideOptions[int]{
+		OnStop: func(ctx context.Context, _ int) error { return errFirst },
+	})
+	slowRef := Provide(func(ctx *Context) int {
+		return 2
+	}, ProvideOptions[int]{
+		OnStop: func(ctx context.Context, _ int) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+
+	RunInInjectionContext(func(ctx *Context) any {
+		Inject(ctx, slowRef)
+		Inject(ctx, firstRef)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	err := StopAndResetGlobalInstances(ctx)
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Error("expected Stop to give up on the slow hook at the deadline")
+	}
+	if !errors.Is(err, errFirst) {
+		t.Errorf("expected errFirst, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	defaultContainer.mu.RLock()
+	defer defaultContainer.mu.RUnlock()
+	if len(defaultContainer.singletons.instances) != 0 || len(defaultContainer.singletons.lifecycles) != 0 {
+		t.Error("expected global instances to be reset")
+	}
+}
@@ -0,0 +1,145 @@
+package ioc
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// MapBinding collects refs providing T under keys of type K, so they can be
+// injected together with InjectMap. Refs from any package can contribute.
+type MapBinding[K comparable, T any] struct {
+	name    string
+	mu      sync.RWMutex
+	keys    []K
+	entries map[K]*Ref[T]
+}
+
+// NewMapBinding creates an empty map binding
+func NewMapBinding[K comparable, T any](name string) *MapBinding[K, T] {
+	return &MapBinding[K, T]{name: name, entries: make(map[K]*Ref[T])}
+}
+
+// Name returns the name of the map binding
+func (m *MapBinding[K, T]) Name() string {
+	return m.name
+}
+
+// String describes the map binding by key type, value type and name
+func (m *MapBinding[K, T]) String() string {
+	key := reflect.TypeOf((*K)(nil)).Elem().String()
+	value := reflect.TypeOf((*T)(nil)).Elem().String()
+	return "MapBinding[" + key + ", " + value + "] " + strconv.Quote(m.name)
+}
+
+// Add contributes ref under key. It panics with a *DuplicateKeyError if the
+// key has already been contributed.
+func (m *MapBinding[K, T]) Add(key K, ref *Ref[T]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.entries[key]; ok {
+		panic(&DuplicateKeyError{Binding: m.String(), Key: key, Refs: []RefInfo{existing.describe(), ref.describe()}})
+	}
+	m.keys = append(m.keys, key)
+	m.entries[key] = ref
+}
+
+// Entries returns the contributed refs by key
+func (m *MapBinding[K, T]) Entries() map[K]*Ref[T] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entries := make(map[K]*Ref[T], len(m.entries))
+	for key, ref := range m.entries {
+		entries[key] = ref
+	}
+	return entries
+}
+
+// entriesIn applies the changes bound in the container and registered in the
+// context chain of ctx to the entries of the map binding, outermost context
+// first. Keys are returned in the order they were contributed.
+func (m *MapBinding[K, T]) entriesIn(ctx *Context) ([]K, map[K]*Ref[T]) {
+	m.mu.RLock()
+	keys := append([]K(nil), m.keys...)
+	entries := make(map[K]*Ref[T], len(m.entries))
+	for key, ref := range m.entries {
+		entries[key] = ref
+	}
+	m.mu.RUnlock()
+
+	for _, current := range ctx.providerChain() {
+		changes, _ := current.localProviders[m].([]mapChange[K, T])
+		for _, change := range changes {
+			_, exists := entries[change.key]
+			switch {
+			case change.remove && exists:
+				delete(entries, change.key)
+				keys = removeKey(keys, change.key)
+			case !change.remove:
+				if !exists {
+					keys = append(keys, change.key)
+				}
+				entries[change.key] = change.ref
+			}
+		}
+	}
+	return keys, entries
+}
+
+// mapChange sets or removes an entry of a map binding for a context subtree
+type mapChange[K comparable, T any] struct {
+	binding *MapBinding[K, T]
+	key     K
+	ref     *Ref[T]
+	remove  bool
+}
+
+// register implements Binding
+func (c mapChange[K, T]) register(ctx *Context) error {
+	if c.binding == nil || (!c.remove && c.ref == nil) {
+		return &ProviderError{Reason: "MapEntry called with a nil map binding or ref"}
+	}
+	changes, _ := ctx.localProviders[c.binding].([]mapChange[K, T])
+	for _, change := range changes {
+		if !c.remove && !change.remove && change.key == c.key {
+			err := &DuplicateKeyError{Binding: c.binding.String(), Key: c.key, Refs: []RefInfo{change.ref.describe(), c.ref.describe()}}
+			return &ProviderError{Provider: c.ref, Err: err}
+		}
+	}
+	ctx.localProviders[c.binding] = append(changes, c)
+	return nil
+}
+
+// MapEntry sets the entry of key to ref for the subtree the binding is
+// registered in, replacing any entry contributed further up
+func MapEntry[K comparable, T any](binding *MapBinding[K, T], key K, ref *Ref[T]) Binding {
+	return mapChange[K, T]{binding: binding, key: key, ref: ref}
+}
+
+// RemoveMapEntry removes the entry of key for the subtree the binding is
+// registered in
+func RemoveMapEntry[K comparable, T any](binding *MapBinding[K, T], key K) Binding {
+	return mapChange[K, T]{binding: binding, key: key, remove: true}
+}
+
+// InjectMap injects every entry of binding, in the order the keys were
+// contributed, taking the entries set and removed by local providers into
+// account. Each entry is injected like a ref passed to Inject.
+func InjectMap[K comparable, T any](ctx *Context, binding *MapBinding[K, T]) map[K]T {
+	keys, entries := binding.entriesIn(ctx)
+	instances := make(map[K]T, len(keys))
+	for _, key := range keys {
+		instances[key] = Inject(ctx, entries[key])
+	}
+	return instances
+}
+
+func removeKey[K comparable](keys []K, key K) []K {
+	kept := make([]K, 0, len(keys))
+	for _, k := range keys {
+		if k != key {
+			kept = append(kept, k)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,122 @@
+package ioc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInjectMap(t *testing.T) {
+	t.Parallel()
+
+	gateways := NewMapBinding[string, string]("gateways")
+	gateways.Add("USD", Provide(func(ctx *Context) string { return "stripe" }))
+	gateways.Add("EUR", Provide(func(ctx *Context) string { return "adyen" }))
+
+	result := RunInContainer(NewContainer(), func(ctx *Context) map[string]string {
+		return InjectMap(ctx, gateways)
+	})
+
+	if len(result) != 2 || result["USD"] != "stripe" || result["EUR"] != "adyen" {
+		t.Errorf("unexpected entries %v", result)
+	}
+}
+
+func TestMapBindingRejectsDuplicateKeys(t *testing.T) {
+	t.Parallel()
+
+	gateways := NewMapBinding[string, string]("gateways")
+	gateways.Add("USD", Provide(func(ctx *Context) string {
+		return "stripe"
+	}, ProvideOptions[string]{Name: "Stripe"}))
+
+	defer func() {
+		err, ok := recover().(*DuplicateKeyError)
+		if !ok {
+			t.Fatal("expected Add to panic with *DuplicateKeyError")
+		}
+		if err.Key != "USD" || len(err.Refs) != 2 || err.Refs[0].Name != "Stripe" || err.Refs[1].Name != "PayPal" {
+			t.Errorf("unexpected error %+v", err)
+		}
+		message := err.Error()
+		if !strings.HasPrefix(message, `Duplicate key USD in MapBinding[string, string] "gateways": contributed by Ref[string] "Stripe"`) ||
+			!strings.Contains(message, ` and Ref[string] "PayPal"`) {
+			t.Errorf("unexpected message '%s'", message)
+		}
+	}()
+	gateways.Add("USD", Provide(func(ctx *Context) string {
+		return "paypal"
+	}, ProvideOptions[string]{Name: "PayPal"}))
+}
+
+func TestMapEntriesOverriddenPerKey(t *testing.T) {
+	t.Parallel()
+
+	gateways := NewMapBinding[string, string]("gateways")
+	gateways.Add("USD", Provide(func(ctx *Context) string { return "stripe" }))
+	gateways.Add("EUR", Provide(func(ctx *Context) string { return "adyen" }))
+	gateways.Add("JPY", Provide(func(ctx *Context) string { return "komoju" }))
+	fakeRef := Provide(func(ctx *Context) string { return "fake" })
+
+	innerRef := Provide(func(ctx *Context) map[string]string {
+		return InjectMap(ctx, gateways)
+	}, ProvideOptions[map[string]string]{Mode: ModeStandalone, Providers: []any{
+		MapEntry(gateways, "EUR", fakeRef),
+	}})
+	outerRef := Provide(func(ctx *Context) []map[string]string {
+		return []map[string]string{InjectMap(ctx, gateways), Inject(ctx, innerRef)}
+	}, ProvideOptions[[]map[string]string]{Providers: []any{
+		MapEntry(gateways, "USD", fakeRef),
+		MapEntry(gateways, "GBP", fakeRef),
+		RemoveMapEntry(gateways, "JPY"),
+	}})
+
+	container := NewContainer()
+	result := RunInContainer(container, func(ctx *Context) []map[string]string {
+		return Inject(ctx, outerRef)
+	})
+	global := RunInContainer(container, func(ctx *Context) map[string]string {
+		return InjectMap(ctx, gateways)
+	})
+
+	outer, inner := result[0], result[1]
+	if len(outer) != 3 || outer["USD"] != "fake" || outer["EUR"] != "adyen" || outer["GBP"] != "fake" {
+		t.Errorf("unexpected outer entries %v", outer)
+	}
+	if len(inner) != 3 || inner["USD"] != "fake" || inner["EUR"] != "fake" || inner["GBP"] != "fake" {
+		t.Errorf("unexpected inner entries %v", inner)
+	}
+	if len(global) != 3 || global["USD"] != "stripe" || global["JPY"] != "komoju" {
+		t.Errorf("expected local changes not to leak, got %v", global)
+	}
+}
+
+func TestMapEntryRejectsDuplicateKeysInOneContext(t *testing.T) {
+	t.Parallel()
+
+	gateways := NewMapBinding[string, string]("gateways")
+	stripeRef := Provide(func(ctx *Context) string { return "stripe" })
+	paypalRef := Provide(func(ctx *Context) string { return "paypal" })
+
+	err := RunInContainer(NewContainer(), func(ctx *Context) error {
+		_, err := InjectE(ctx, Provide(func(ctx *Context) map[string]string {
+			return InjectMap(ctx, gateways)
+		}, ProvideOptions[map[string]string]{Providers: []any{
+			MapEntry(gateways, "USD", stripeRef),
+			MapEntry(gateways, "USD", paypalRef),
+		}}))
+		return err
+	})
+
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || !strings.Contains(err.Error(), "Duplicate key USD") {
+		t.Errorf("expected a duplicate key error, got %v", err)
+	}
+	var dupErr *DuplicateKeyError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected the *DuplicateKeyError to be wrapped, got %v", err)
+	}
+	if dupErr.Key != "USD" || len(dupErr.Refs) != 2 || dupErr.Refs[0].Ref != stripeRef || dupErr.Refs[1].Ref != paypalRef {
+		t.Errorf("expected both contributions of USD, got %+v", dupErr)
+	}
+}
@@ -0,0 +1,38 @@
+package ioc
+
+// NewScope opens a scope below ctx. ModeScoped refs injected anywhere below
+// the scope share one instance, which is disposed when the scope is closed.
+// The scope is also closed together with ctx.
+func (ctx *Context) NewScope() *Context {
+	scope := createContext(ctx.container, ctx)
+	scope.scope = true
+	ctx.trackChild(scope)
+	return scope
+}
+
+// resolveScoped resolves ref in the nearest scope at or above the context its
+// provider was registered in, or at or above ctx if it was not overridden
+func resolveScoped[T any](ctx *Context, ref *Ref[T], owner *Context) (T, error) {
+	start := ctx
+	if owner != nil {
+		start = owner
+	}
+	scope := start.nearestScope()
+
+	// Dependencies are resolved from where the provider was registered, so
+	// the instance does not pick up overrides of a single child context
+	home := scope
+	if owner != nil {
+		home = owner
+	}
+	return resolveCached(ctx, ref, scope, home)
+}
+
+// nearestScope returns the closest scope at or above ctx
+func (ctx *Context) nearestScope() *Context {
+	current := ctx
+	for !current.scope {
+		current = current.parent
+	}
+	return current
+}
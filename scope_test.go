@@ -0,0 +1,191 @@
+package ioc
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestScopedSharedAcrossProviderContextsInScope(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	type Tx struct {
+		ID int32
+	}
+
+	txRef := Provide(func(ctx *Context) *Tx {
+		return &Tx{ID: atomic.AddInt32(&counter, 1)}
+	}, ProvideOptions[*Tx]{Mode: ModeScoped})
+
+	unrelatedRef := Provide(func(ctx *Context) string { return "unrelated" })
+
+	type Repo struct {
+		Tx *Tx
+	}
+
+	// Both repos create child contexts for their local providers
+	ordersRef := Provide(func(ctx *Context) *Repo {
+		return &Repo{Tx: Inject(ctx, txRef)}
+	}, ProvideOptions[*Repo]{Mode: ModeStandalone, Providers: []any{unrelatedRef}})
+	usersRef := Provide(func(ctx *Context) *Repo {
+		return &Repo{Tx: Inject(ctx, txRef)}
+	}, ProvideOptions[*Repo]{Mode: ModeStandalone, Providers: []any{unrelatedRef}})
+
+	ctx := NewContext()
+	scope := ctx.NewScope()
+
+	orders := Inject(scope, ordersRef)
+	users := Inject(scope, usersRef)
+	direct := Inject(scope, txRef)
+
+	if orders.Tx != users.Tx || users.Tx != direct {
+		t.Error("expected one scoped instance shared below the scope")
+	}
+	if counter != 1 {
+		t.Errorf("expected counter to be 1, got %d", counter)
+	}
+
+	other := ctx.NewScope()
+	if Inject(other, txRef) == direct {
+		t.Error("expected a different instance in a different scope")
+	}
+}
+
+func TestNestedScopesHaveTheirOwnInstances(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&counter, 1)
+	}, ProvideOptions[int32]{Mode: ModeScoped})
+
+	root := NewContext()
+	outer := root.NewScope()
+	inner := outer.NewScope()
+
+	if Inject(root, ref) != 1 {
+		t.Error("expected the root context to act as the outermost scope")
+	}
+	if Inject(outer, ref) != 2 || Inject(inner, ref) != 3 {
+		t.Error("expected each scope to construct its own instance")
+	}
+	if Inject(inner, ref) != 3 || Inject(outer, ref) != 2 {
+		t.Error("expected each scope to keep its instance")
+	}
+}
+
+func TestScopedInstanceIgnoresOverridesOfChildContexts(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	configRef := Provide(func(ctx *Context) string {
+		return "global"
+	})
+	testConfigRef := Provide(func(ctx *Context) string {
+		return "test"
+	}, ProvideOptions[string]{Overrides: configRef})
+
+	type Client struct {
+		ID     int32
+		Config string
+	}
+	clientRef := Provide(func(ctx *Context) *Client {
+		return &Client{ID: atomic.AddInt32(&counter, 1), Config: Inject(ctx, configRef)}
+	}, ProvideOptions[*Client]{Mode: ModeScoped})
+
+	serviceRef := Provide(func(ctx *Context) *Client {
+		return Inject(ctx, clientRef)
+	}, ProvideOptions[*Client]{Mode: ModeStandalone, Providers: []any{testConfigRef}})
+
+	scope := NewContext().NewScope()
+	fromService := Inject(scope, serviceRef)
+	direct := Inject(scope, clientRef)
+
+	// The scoped client is constructed from the scope, not from the child
+	// context of serviceRef, so it is shared and sees no local overrides
+	if fromService != direct {
+		t.Error("expected the scoped client to be shared")
+	}
+	if direct.Config != "global" {
+		t.Errorf("expected 'global', got '%s'", direct.Config)
+	}
+}
+
+func TestOverriddenScopedRefResolvesFromRegistration(t *testing.T) {
+	ResetGlobalInstances()
+
+	configRef := Provide(func(ctx *Context) string {
+		return "global"
+	})
+	testConfigRef := Provide(func(ctx *Context) string {
+		return "test"
+	}, ProvideOptions[string]{Overrides: configRef})
+
+	clientRef := Provide(func(ctx *Context) string {
+		return "client:" + Inject(ctx, configRef)
+	}, ProvideOptions[string]{Mode: ModeScoped})
+	testClientRef := Provide(func(ctx *Context) string {
+		return "test client:" + Inject(ctx, configRef)
+	}, ProvideOptions[string]{Mode: ModeScoped, Overrides: clientRef})
+
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, clientRef)
+	}, ProvideOptions[string]{Mode: ModeStandalone, Providers: []any{testConfigRef, testClientRef}})
+
+	scope := NewContext().NewScope()
+
+	if value := Inject(scope, serviceRef); value != "test client:test" {
+		t.Errorf("expected 'test client:test', got '%s'", value)
+	}
+	if value := Inject(scope, clientRef); value != "client:global" {
+		t.Errorf("expected 'client:global', got '%s'", value)
+	}
+}
+
+func TestGlobalRefInScopeIsSingleton(t *testing.T) {
+	ResetGlobalInstances()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&counter, 1)
+	})
+
+	first := NewContext().NewScope()
+	second := NewContext().NewScope()
+
+	if Inject(first, ref) != Inject(second, ref) {
+		t.Error("expected global refs to stay singletons inside scopes")
+	}
+	if counter != 1 {
+		t.Errorf("expected counter to be 1, got %d", counter)
+	}
+}
+
+func TestClosingScopeDisposesScopedInstances(t *testing.T) {
+	ResetGlobalInstances()
+
+	var closed []string
+	ref := Provide(func(ctx *Context) *closable {
+		return &closable{name: "tx", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeScoped})
+
+	ctx := NewContext()
+	first := ctx.NewScope()
+	second := ctx.NewScope()
+	Inject(first, ref)
+	Inject(second, ref)
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(closed) != 1 {
+		t.Errorf("expected one instance to be closed with its scope, got %v", closed)
+	}
+
+	if err := ctx.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(closed) != 2 {
+		t.Errorf("expected remaining scopes to be closed with the root, got %v", closed)
+	}
+}
@@ -0,0 +1,41 @@
+package ioc
+
+import "context"
+
+// contextKey is the key an injection context is stored under in a
+// context.Context
+type contextKey struct{}
+
+// Context returns the context.Context attached to the injection context, or
+// context.Background if none was attached. Factories can use it to observe
+// deadlines, cancellation and request-scoped values.
+func (ctx *Context) Context() context.Context {
+	if ctx.std == nil {
+		return context.Background()
+	}
+	return ctx.std
+}
+
+// WithContext returns a view of the injection context with std attached. The
+// view shares its instances, providers and disposal with ctx, and contexts
+// created below it inherit std.
+func (ctx *Context) WithContext(std context.Context) *Context {
+	if std == nil {
+		panic("nil context")
+	}
+	view := *ctx
+	view.std = std
+	return &view
+}
+
+// WithContext returns a copy of parent carrying the injection context, so it
+// can be handed down through code that only passes a context.Context
+func WithContext(parent context.Context, ctx *Context) context.Context {
+	return context.WithValue(parent, contextKey{}, ctx)
+}
+
+// FromContext returns the injection context stored in std by WithContext
+func FromContext(std context.Context) (*Context, bool) {
+	ctx, ok := std.Value(contextKey{}).(*Context)
+	return ctx, ok
+}
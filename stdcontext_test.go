@@ -0,0 +1,92 @@
+package ioc
+
+import (
+	"context"
+	"testing"
+)
+
+type traceIDKey struct{}
+
+func TestFactoriesSeeAttachedContext(t *testing.T) {
+	ResetGlobalInstances()
+
+	type Logger struct {
+		TraceID string
+	}
+
+	loggerRef := Provide(func(ctx *Context) *Logger {
+		traceID, _ := ctx.Context().Value(traceIDKey{}).(string)
+		return &Logger{TraceID: traceID}
+	}, ProvideOptions[*Logger]{Mode: ModeStandalone})
+
+	type Handler struct {
+		Logger *Logger
+	}
+
+	localLoggerRef := Provide(func(ctx *Context) *Logger {
+		traceID, _ := ctx.Context().Value(traceIDKey{}).(string)
+		return &Logger{TraceID: "local " + traceID}
+	}, ProvideOptions[*Logger]{Mode: ModeStandalone, Overrides: loggerRef})
+
+	handlerRef := Provide(func(ctx *Context) *Handler {
+		return &Handler{Logger: Inject(ctx, loggerRef)}
+	}, ProvideOptions[*Handler]{
+		Mode:      ModeStandalone,
+		Providers: []any{localLoggerRef},
+	})
+
+	std := context.WithValue(context.Background(), traceIDKey{}, "abc123")
+	ctx := NewContext().WithContext(std)
+
+	if logger := Inject(ctx, loggerRef); logger.TraceID != "abc123" {
+		t.Errorf("expected trace ID 'abc123', got '%s'", logger.TraceID)
+	}
+	if handler := Inject(ctx, handlerRef); handler.Logger.TraceID != "local abc123" {
+		t.Errorf("expected child contexts to inherit the attached context, got '%s'", handler.Logger.TraceID)
+	}
+}
+
+func TestContextDefaultsToBackground(t *testing.T) {
+	ctx := NewContext()
+	if ctx.Context() != context.Background() {
+		t.Error("expected context.Background when nothing is attached")
+	}
+}
+
+func TestWithContextSharesInstances(t *testing.T) {
+	ResetGlobalInstances()
+
+	ref := Provide(func(ctx *Context) *int {
+		value := 0
+		return &value
+	}, ProvideOptions[*int]{Mode: ModeStandalone})
+
+	ctx := NewContext()
+	std, cancel := context.WithCancel(context.Background())
+	view := ctx.WithContext(std)
+	cancel()
+
+	if Inject(ctx, ref) != Inject(view, ref) {
+		t.Error("expected the view to share instances with the original context")
+	}
+	if view.Context().Err() == nil {
+		t.Error("expected cancellation to be visible through the view")
+	}
+	if ctx.Context().Err() != nil {
+		t.Error("expected the original context to be unaffected")
+	}
+}
+
+func TestFromContextReturnsStoredInjectionContext(t *testing.T) {
+	ctx := NewContext()
+	std := WithContext(context.Background(), ctx)
+
+	got, ok := FromContext(std)
+	if !ok || got != ctx {
+		t.Error("expected to get back the stored injection context")
+	}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no injection context in a plain context")
+	}
+}
@@ -0,0 +1,21 @@
+package ioc
+
+// Token creates a ref without a factory, for a dependency the composition
+// root has to supply by overriding it, for example with Override or Value.
+// Injecting a token that is not overridden in the context chain fails with
+// a *ResolutionError wrapping ErrNotProvided.
+func Token[T any](name string) *Ref[T] {
+	ref := newRef[T](nil, []ProvideOptions[T]{{Name: name}})
+	ref.token = true
+	return ref
+}
+
+// InjectOptional injects ref, reporting false instead of failing if it is a
+// token that is not overridden. Any other failure panics like Inject.
+func InjectOptional[T any](ctx *Context, ref *Ref[T]) (T, bool) {
+	if actualRef, _ := findRefInContext(ctx, ref); actualRef.token {
+		var zero T
+		return zero, false
+	}
+	return Inject(ctx, ref), true
+}
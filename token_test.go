@@ -0,0 +1,98 @@
+package ioc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type orderRepository interface{ Find(id string) string }
+
+type memoryOrderRepository struct{}
+
+func (memoryOrderRepository) Find(id string) string { return "order " + id }
+
+func TestTokenFailsWithoutOverride(t *testing.T) {
+	t.Parallel()
+
+	repositoryRef := Token[orderRepository]("OrderRepository")
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, repositoryRef).Find("1")
+	}, ProvideOptions[string]{Name: "Service"})
+
+	err := RunInContainer(NewContainer(), func(ctx *Context) error {
+		_, err := InjectE(ctx, serviceRef)
+		return err
+	})
+
+	if !errors.Is(err, ErrNotProvided) {
+		t.Fatalf("expected ErrNotProvided, got %v", err)
+	}
+	var resErr *ResolutionError
+	if !errors.As(err, &resErr) || len(resErr.Chain) != 2 || resErr.Chain[1].Ref != repositoryRef {
+		t.Errorf("expected the chain to end at the token, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `Ref[ioc.orderRepository] "OrderRepository"`) {
+		t.Errorf("expected the token to be named, got '%v'", err)
+	}
+}
+
+func TestTokenResolvesToOverride(t *testing.T) {
+	t.Parallel()
+
+	repositoryRef := Token[orderRepository]("OrderRepository")
+	memoryRef := Provide(func(ctx *Context) orderRepository { return memoryOrderRepository{} })
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, repositoryRef).Find("1")
+	})
+
+	result := RunInContainer(NewContainer(), func(ctx *Context) string {
+		return Inject(ctx, serviceRef)
+	}, WithProviders(Override(repositoryRef, memoryRef)))
+
+	if result != "order 1" {
+		t.Errorf("expected 'order 1', got '%s'", result)
+	}
+}
+
+func TestInjectOptional(t *testing.T) {
+	t.Parallel()
+
+	tracerRef := Token[string]("Tracer")
+	loggerRef := Provide(func(ctx *Context) string { return "logger" })
+
+	container := NewContainer()
+	absent, absentOK := InjectOptional(container.NewContext(), tracerRef)
+	present, presentOK := InjectOptional(container.NewContext(WithProviders(Value(tracerRef, "otel"))), tracerRef)
+	logger, loggerOK := InjectOptional(container.NewContext(), loggerRef)
+
+	if absent != "" || absentOK {
+		t.Errorf("expected an absent token, got '%s', %v", absent, absentOK)
+	}
+	if present != "otel" || !presentOK {
+		t.Errorf("expected the overridden token, got '%s', %v", present, presentOK)
+	}
+	if logger != "logger" || !loggerOK {
+		t.Errorf("expected a regular ref to be injected, got '%s', %v", logger, loggerOK)
+	}
+}
+
+func TestValidateReportsMissingTokens(t *testing.T) {
+	t.Parallel()
+
+	repositoryRef := Token[orderRepository]("OrderRepository")
+	serviceRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, repositoryRef).Find("1")
+	})
+
+	container := NewContainer()
+	container.Register(serviceRef)
+
+	if err := container.Validate(); !errors.Is(err, ErrNotProvided) {
+		t.Errorf("expected Validate to report the missing token, got %v", err)
+	}
+	container.Bind(Value[orderRepository](repositoryRef, memoryOrderRepository{}))
+	if err := container.Validate(); err != nil {
+		t.Errorf("expected Validate to see the token bound in the container, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package ioc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError lists every problem found by Validate
+type ValidationError struct {
+	Errors []error
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Validation found %d problem(s):", len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n  - " + err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the individual problems
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// Register adds root refs for the default container to check in Validate
+func Register(refs ...any) {
+	defaultContainer.Register(refs...)
+}
+
+// Validate checks the refs registered in the default container
+func Validate() error {
+	return defaultContainer.Validate()
+}
+
+// Register adds root refs for Validate to check. It panics if a value is not
+// a ref.
+func (c *Container) Register(refs ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ref := range refs {
+		marker, ok := ref.(refMarker)
+		if !ok {
+			panic(fmt.Sprintf("Register expects refs, got %T", ref))
+		}
+		c.roots = append(c.roots, marker)
+	}
+}
+
+// Validate resolves every registered root ref, with all of its dependencies,
+// in a sandbox container, so the singletons of c are neither created nor
+// replaced. Each root is resolved in its own context, and the cycles, factory
+// errors and panics found are returned together in a *ValidationError, with
+// a problem shared by several roots reported once. A root stops at its first
+// failure, so the dependencies it had not reached yet are only checked if
+// another root reaches them. Factories do run, so instances created in the
+// sandbox, global ones included, are disposed of afterwards as if their
+// context was closed; lifecycle hooks are not run.
+func (c *Container) Validate() error {
+	c.mu.RLock()
+	roots := append([]refMarker(nil), c.roots...)
+	c.mu.RUnlock()
+
+	sandbox := NewContainer()
+	sandbox.bindings = c.bound()
+	sandbox.singletons = newSingletons(&disposer{})
+	var problems []error
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		ctx := sandbox.NewContext()
+		_, err := root.injectAny(ctx)
+		if closeErr := ctx.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+		if err == nil {
+			continue
+		}
+		key := problemKey(err)
+		if !seen[key] {
+			seen[key] = true
+			problems = append(problems, err)
+		}
+	}
+	if err := sandbox.singletons.disposer.close(); err != nil {
+		problems = append(problems, err)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: problems}
+}
+
+// problemKey identifies the cause of a resolution error independently of the
+// root it was reached from
+func problemKey(err error) string {
+	var cycleErr *CircularDependencyError
+	if errors.As(err, &cycleErr) {
+		return "cycle " + cycleKey(cycleErr.Path)
+	}
+
+	var resErr *ResolutionError
+	if errors.As(err, &resErr) && len(resErr.Chain) > 0 {
+		return fmt.Sprintf("error %p %v", resErr.Chain[len(resErr.Chain)-1].Ref, resErr.Err)
+	}
+
+	var panicErr *FactoryPanicError
+	if errors.As(err, &panicErr) && len(panicErr.Chain) > 0 {
+		return fmt.Sprintf("panic %p %v", panicErr.Chain[len(panicErr.Chain)-1].Ref, panicErr.Value)
+	}
+
+	return "other " + err.Error()
+}
+
+// cycleKey returns the refs of the loop at the end of path, rotated to start
+// at the lowest address so every entry point yields the same key
+func cycleKey(path []RefInfo) string {
+	if len(path) == 0 {
+		return ""
+	}
+	last := path[len(path)-1].Ref
+	start := 0
+	for i, info := range path {
+		if info.Ref == last {
+			start = i
+			break
+		}
+	}
+
+	loop := make([]string, 0, len(path)-start-1)
+	for _, info := range path[start : len(path)-1] {
+		loop = append(loop, fmt.Sprintf("%p", info.Ref))
+	}
+	lowest := 0
+	for i := range loop {
+		if loop[i] < loop[lowest] {
+			lowest = i
+		}
+	}
+	return strings.Join(append(loop[lowest:], loop[:lowest]...), " ")
+}
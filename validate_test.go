@@ -0,0 +1,150 @@
+package ioc
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	errOpen := errors.New("cannot open database")
+
+	dbRef := ProvideE(func(ctx *Context) (string, error) {
+		return "", errOpen
+	}, ProvideOptions[string]{Name: "db"})
+	ordersRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, dbRef)
+	}, ProvideOptions[string]{Name: "orders"})
+	usersRef := Provide(func(ctx *Context) string {
+		return Inject(ctx, dbRef)
+	}, ProvideOptions[string]{Name: "users"})
+
+	var aRef, bRef *Ref[string]
+	aRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, bRef)
+	}, ProvideOptions[string]{Name: "a"})
+	bRef = Provide(func(ctx *Context) string {
+		return Inject(ctx, aRef)
+	}, ProvideOptions[string]{Name: "b"})
+
+	panickingRef := Provide(func(ctx *Context) string {
+		panic("not configured")
+	}, ProvideOptions[string]{Name: "panicking"})
+
+	healthyRef := Provide(func(ctx *Context) string {
+		return "healthy"
+	}, ProvideOptions[string]{Name: "healthy"})
+
+	container := NewContainer()
+	container.Register(ordersRef, usersRef, aRef, bRef, panickingRef, healthyRef)
+
+	err := container.Validate()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	// The database failure and the cycle are each reported once, even
+	// though two roots reach them
+	if len(validationErr.Errors) != 3 {
+		t.Fatalf("expected 3 problems, got %v", validationErr.Errors)
+	}
+	if !errors.Is(err, errOpen) {
+		t.Error("expected the database error to be reported")
+	}
+	var cycleErr *CircularDependencyError
+	if !errors.As(err, &cycleErr) {
+		t.Error("expected the cycle to be reported")
+	}
+	var panicErr *FactoryPanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "not configured" {
+		t.Error("expected the panic to be reported")
+	}
+	if !strings.HasPrefix(err.Error(), "Validation found 3 problem(s):") {
+		t.Errorf("unexpected message '%s'", err.Error())
+	}
+}
+
+func TestValidateUsesSandboxContainer(t *testing.T) {
+	t.Parallel()
+
+	var counter int32
+	ref := Provide(func(ctx *Context) int32 {
+		return atomic.AddInt32(&counter, 1)
+	})
+
+	container := NewContainer()
+	container.RecordGraph()
+	container.Register(ref)
+
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if counter != 1 {
+		t.Errorf("expected validation to construct the ref once, got %d", counter)
+	}
+
+	// The real container still constructs its own singleton
+	if value := Inject(container.NewContext(), ref); value != 2 {
+		t.Errorf("expected a fresh singleton, got %d", value)
+	}
+	if len(container.Graph().Nodes) != 1 {
+		t.Error("expected validation not to record into the container graph")
+	}
+}
+
+func TestValidateDisposesSandboxInstances(t *testing.T) {
+	t.Parallel()
+
+	var closed []string
+	ref := Provide(func(ctx *Context) *closable {
+		return &closable{name: "conn", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+
+	container := NewContainer()
+	container.Register(ref)
+
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(closed) != 1 {
+		t.Errorf("expected the sandbox instance to be closed, got %v", closed)
+	}
+}
+
+func TestValidateDisposesSandboxGlobals(t *testing.T) {
+	t.Parallel()
+
+	var closed []string
+	connRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "conn", closed: &closed}
+	}, ProvideOptions[*closable]{Mode: ModeStandalone})
+	poolRef := Provide(func(ctx *Context) *closable {
+		return &closable{name: "pool:" + Inject(ctx, connRef).name, closed: &closed}
+	})
+
+	container := NewContainer()
+	container.Register(poolRef, poolRef)
+
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(closed) != 2 || closed[0] != "pool:conn" || closed[1] != "conn" {
+		t.Errorf("expected the sandbox singleton to be closed before its dependency, got %v", closed)
+	}
+}
+
+func TestRegisterRejectsNonRefs(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for a value that is not a ref")
+		}
+	}()
+
+	NewContainer().Register("not a ref")
+}